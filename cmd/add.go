@@ -7,7 +7,6 @@ import (
 	"snippetkit/internal"
 	"strings"
 
-	"github.com/leaanthony/spinner"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 )
@@ -16,6 +15,8 @@ import (
 var addPath string
 var addForce bool
 var addSilent bool
+var addMerge bool
+var addDryRun bool
 
 func init() {
 	rootCmd.AddCommand(addCmd)
@@ -24,6 +25,8 @@ func init() {
 	addCmd.Flags().StringVarP(&addPath, "path", "p", "", "Specify install path for the snippet")
 	addCmd.Flags().BoolVarP(&addForce, "force", "f", false, "Force overwrite if file exists")
 	addCmd.Flags().BoolVarP(&addSilent, "silent", "s", false, "Suppress output")
+	addCmd.Flags().BoolVarP(&addMerge, "merge", "m", false, "Merge a Go snippet into an existing file instead of skipping it")
+	addCmd.Flags().BoolVar(&addDryRun, "dry-run", false, "Show the install plan without writing any files")
 }
 
 // addCmd represents the add command
@@ -31,25 +34,17 @@ var addCmd = &cobra.Command{
 	Use:   "add [snippet ID]",
 	Short: "Fetch and add a snippet to your project",
 	Long:  `Fetch a snippet from SnippetKit API and install it into your project.`,
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		snippetID := args[0]
-		// Show spinner while checking API status
-		checkSpinner := spinner.New()
-		checkSpinner.Start("Checking auth status...")
-		// Load API token from config file
-		apiToken, err := internal.GetAPIKey()
+		// Check API status before fetching
+		apiToken, err := requireAPIKey()
 		if err != nil {
-			checkSpinner.Error("Failed to authenticate")
-			fmt.Print("Error authenticating. Run 'snippetkit login' to authenticate.")
-			internal.Error("Failed to get API key", err, nil)
-			return
+			return err
 		}
 
-		checkSpinner.Success("Authenticated successfully")
-
 		// Show fancy spinner while fetching snippet
-		myspinner := spinner.New()
+		myspinner := internal.NewSpinner()
 		myspinner.Start(fmt.Sprintf("Fetching snippet %s...", snippetID))
 
 		// Fetch snippet from API
@@ -57,110 +52,180 @@ var addCmd = &cobra.Command{
 		if err != nil {
 			myspinner.Error(fmt.Sprintf("Failed to fetch snippet with ID: %s", snippetID))
 			internal.Error("Failed to fetch snippet", err, nil)
-			return
+			return fmt.Errorf("failed to fetch snippet %s: %w", snippetID, err)
 		}
 		myspinner.Success(fmt.Sprintf("Snippet %s fetched successfully", snippetID))
 
-		// Show snippet info
-		if !addSilent {
-			fmt.Println(titleStyle.Render("> Snippet Details:"))
-			fmt.Println(divider)
-			fmt.Println(labelStyle.Render("Title: ") + snippet.Title)
-			fmt.Println(labelStyle.Render("Language: ") + snippet.Language)
-			fmt.Println(labelStyle.Render("Tags: ") + strings.Join(snippet.Tags, ", "))
+		return installSnippet(snippet)
+	},
+}
+
+// installSnippet writes a fetched snippet into the project, honoring the add
+// flags and prompting for the install path when possible
+func installSnippet(snippet *internal.Snippet) error {
+	// Quiet and machine readable output imply silent mode
+	silent := addSilent || !internal.ProgressEnabled()
+
+	// Show snippet info
+	if !silent {
+		statusln(ui.Title.Render("> Snippet Details:"))
+		statusln(ui.Divider())
+		statusln(ui.Label.Render("Title: ") + snippet.Title)
+		statusln(ui.Label.Render("Language: ") + snippet.Language)
+		statusln(ui.Label.Render("Tags: ") + strings.Join(snippet.Tags, ", "))
+	}
+	// Determine install path
+	var installPath string
+
+	if addPath != "" {
+		installPath = addPath // Use provided path
+	} else {
+		cwd, _ := os.Getwd()
+		defaultPath := filepath.Join(cwd, snippet.Path)
+		if snippet.Path == "" {
+			defaultPath = filepath.Join(cwd, fmt.Sprintf("%s.%s", snippet.Title, snippet.Language))
 		}
-		// Determine install path
-		var installPath string
 
-		if addPath != "" {
-			installPath = addPath // Use provided path
+		if !silent {
+			statusf("%s %s\n", ui.Title.Render("Default install path:"), defaultPath)
+		}
+
+		// Skip prompt in silent or non-interactive mode
+		if silent || internal.AssumeYes() || !internal.CanPrompt() {
+			installPath = defaultPath
 		} else {
-			cwd, _ := os.Getwd()
-			defaultPath := filepath.Join(cwd, snippet.Path)
-			if snippet.Path == "" {
-				defaultPath = filepath.Join(cwd, fmt.Sprintf("%s.%s", snippet.Title, snippet.Language))
+			// Prompt user if they want to change the default install path
+			prompt := promptui.Select{
+				Label:  "Do you want to change the install path?",
+				Items:  []string{"No", "Yes"},
+				Stdout: os.Stderr,
 			}
 
-			if !addSilent {
-				fmt.Printf("%s %s\n", titleStyle.Render("Default install path:"), defaultPath)
-			}
+			_, choice, err := prompt.Run()
 
-			// Skip prompt in silent mode
-			if addSilent {
-				installPath = defaultPath
-			} else {
-				// Prompt user if they want to change the default install path
-				prompt := promptui.Select{
-					Label: "Do you want to change the install path?",
-					Items: []string{"No", "Yes"},
-				}
+			if err == promptui.ErrInterrupt {
+				internal.Error("Prompt cancelled by user", nil, nil)
+				return fmt.Errorf("operation cancelled by user")
+			}
 
-				_, choice, err := prompt.Run()
+			if err != nil {
+				return fmt.Errorf("error reading input: %v", err)
+			}
 
+			if choice == "Yes" {
+				pathPrompt := promptui.Prompt{
+					Label:  "Enter new install path",
+					Stdout: os.Stderr,
+					Validate: func(input string) error {
+						if len(input) == 0 {
+							return fmt.Errorf("path cannot be empty")
+						}
+						return nil
+					},
+				}
+				installPath, err = pathPrompt.Run()
 				if err == promptui.ErrInterrupt {
-					fmt.Println(errorStyle.Render("\n Operation cancelled by user."))
-					internal.Error("Prompt cancelled by user", nil, nil)
-					os.Exit(1)
+					return fmt.Errorf("operation cancelled by user")
 				}
-
 				if err != nil {
-					fmt.Println(errorStyle.Render("\nError reading input."))
-					return
-				}
-
-				if choice == "Yes" {
-					pathPrompt := promptui.Prompt{
-						Label: "Enter new install path",
-						Validate: func(input string) error {
-							if len(input) == 0 {
-								return fmt.Errorf("path cannot be empty")
-							}
-							return nil
-						},
-					}
-					installPath, err = pathPrompt.Run()
-					if err == promptui.ErrInterrupt {
-						fmt.Println(errorStyle.Render("\n Operation cancelled by user."))
-						os.Exit(1)
-					}
-					if err != nil {
-						fmt.Println(errorStyle.Render("Error: Could not read input."))
-						return
-					}
-				} else {
-					installPath = defaultPath
+					return fmt.Errorf("could not read input: %v", err)
 				}
+			} else {
+				installPath = defaultPath
 			}
 		}
-
-		// Ensure parent directory exists
-		parentDir := filepath.Dir(installPath)
-		if parentDir != "." && !internal.FileExists(parentDir) {
-			if err := os.MkdirAll(parentDir, 0755); err != nil {
-				fmt.Println(errorStyle.Render(fmt.Sprintf("Failed to create directory: %s", parentDir)))
-				internal.Error(fmt.Sprintf("Error creating directory: %s", parentDir), err, nil)
-				return
-			}
+	}
+
+	parentDir := filepath.Dir(installPath)
+	code := snippet.Code
+	exists := internal.FileExists(installPath)
+	isGo := internal.IsGoSnippet(snippet.Language, installPath)
+	plan := installPlan{
+		Snippet: newSnippetView(snippet, false),
+		Path:    installPath,
+		Action:  "create",
+		DryRun:  addDryRun,
+	}
+
+	// Merge Go snippets into an existing file when asked to
+	if exists && addMerge && !addForce {
+		if !isGo {
+			internal.Warn("Merge requested for a non-Go snippet", nil)
+			return exitErrorf(ExitConflict, "file %s already exists and only Go snippets can be merged", installPath)
 		}
-
+		existing, err := os.ReadFile(installPath)
+		if err != nil {
+			internal.Error("Error reading existing file", err, nil)
+			return fmt.Errorf("failed to read file %s: %v", installPath, err)
+		}
+		code, err = internal.MergeGoSource(string(existing), code)
+		if err != nil {
+			internal.Error("Error merging Go snippet", err, nil)
+			return exitErrorf(ExitConflict, "failed to merge snippet into %s: %v", installPath, err)
+		}
+		plan.Action = "merge"
+	} else {
 		// Handle overwrite
-		if !addForce && internal.FileExists(installPath) {
-			fmt.Println(warningStyle.Render(fmt.Sprintf("\n Skipping installation. File %s already exists. Use --force to overwrite.", installPath)))
+		if !addForce && exists {
 			internal.Warn("File already exists. Use --force to overwrite.", nil)
-			return
+			return exitErrorf(ExitConflict, "file %s already exists. Use --force to overwrite or --merge to insert a Go snippet", installPath)
+		}
+		if exists {
+			plan.Action = "overwrite"
 		}
 
-		// Write snippet to file
-		if err := internal.WriteToFile(installPath, snippet.Code); err != nil {
-			fmt.Println(errorStyle.Render(fmt.Sprintf("Failed to write snippet to file: %s", installPath)))
-			internal.Error("Error writing snippet", err, nil)
-			return
+		// Match the package of the Go files already in the target directory
+		if isGo {
+			pkg, err := internal.DetectGoPackage(parentDir)
+			if err != nil {
+				internal.Warn("Failed to detect Go package", map[string]interface{}{"dir": parentDir, "error": err.Error()})
+			}
+			if pkg != "" {
+				rewritten, err := internal.SetGoPackage(code, pkg)
+				if err != nil {
+					internal.Warn("Failed to rewrite package clause, installing snippet as is", map[string]interface{}{"error": err.Error()})
+				} else {
+					code = rewritten
+					plan.Package = pkg
+					internal.Debug("Rewrote package clause", map[string]interface{}{"package": pkg})
+				}
+			}
 		}
+	}
 
-		// Show success message
-		if !addSilent {
-			fmt.Println(successStyle.Render("\n Snippet installed successfully!"))
+	if addDryRun {
+		if output.machine() {
+			return writeResult(os.Stdout, schemaInstall, plan)
 		}
-		internal.Info(fmt.Sprintf("Snippet installed successfully at %s", installPath), nil)
-	},
+		fmt.Fprintf(internal.Stdout, "%s %s %s\n", ui.Title.Render("Dry run:"), plan.Action, installPath)
+		if plan.Package != "" {
+			fmt.Fprintln(internal.Stdout, ui.Label.Render("Package: ")+plan.Package)
+		}
+		return nil
+	}
+
+	// Ensure parent directory exists
+	if parentDir != "." && !internal.FileExists(parentDir) {
+		if err := os.MkdirAll(parentDir, 0755); err != nil {
+			internal.Error(fmt.Sprintf("Error creating directory: %s", parentDir), err, nil)
+			return fmt.Errorf("failed to create directory %s: %v", parentDir, err)
+		}
+	}
+
+	// Write snippet to file
+	if err := internal.WriteToFile(installPath, code); err != nil {
+		internal.Error("Error writing snippet", err, nil)
+		return fmt.Errorf("failed to write snippet to file %s: %v", installPath, err)
+	}
+	internal.Info(fmt.Sprintf("Snippet installed successfully at %s", installPath), nil)
+
+	if output.machine() {
+		return writeResult(os.Stdout, schemaInstall, plan)
+	}
+
+	// Show success message
+	if !silent {
+		statusln(ui.Success.Render("\n Snippet installed successfully!"))
+	}
+	return nil
 }
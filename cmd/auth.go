@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"snippetkit/internal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// authCmd groups the authentication commands
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Inspect authentication",
+}
+
+// authStatusCmd shows the account and token of the active profile
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the account and API token in use",
+	Long: `Show the account, token source, masked token, expiry and scopes of the active
+profile. The token is verified with the API unless --offline is set. Exits with
+code 3 when not logged in or the token is invalid.`,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		status, statusErr := loadAuthStatus()
+		if output.machine() {
+			if err := writeResult(os.Stdout, schemaAuth, status); err != nil {
+				return err
+			}
+		} else {
+			printAuthStatus(status)
+		}
+		if statusErr != nil {
+			return reportedError(exitCode(statusErr), statusErr)
+		}
+		return nil
+	},
+}
+
+// whoamiCmd is a shortcut for auth status
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the account and API token in use (auth status)",
+	Long:  authStatusCmd.Long,
+	Args:  usageArgs(cobra.NoArgs),
+	RunE:  authStatusCmd.RunE,
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd, whoamiCmd)
+	authCmd.AddCommand(authStatusCmd)
+}
+
+// authStatus is the machine readable result of auth status
+type authStatus struct {
+	Profile       string     `json:"profile" yaml:"profile"`
+	BaseURL       string     `json:"base_url" yaml:"base_url"`
+	Authenticated bool       `json:"authenticated" yaml:"authenticated"`
+	Verified      bool       `json:"verified" yaml:"verified"` // false when offline or unreachable
+	TokenSource   string     `json:"token_source,omitempty" yaml:"token_source,omitempty"`
+	Token         string     `json:"token,omitempty" yaml:"token,omitempty"` // Masked
+	Account       string     `json:"account,omitempty" yaml:"account,omitempty"`
+	Email         string     `json:"email,omitempty" yaml:"email,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	Scopes        []string   `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	Error         string     `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// loadAuthStatus reads and verifies the active profile's token. The error
+// says why the user isn't authenticated.
+func loadAuthStatus() (*authStatus, error) {
+	profile, err := internal.ActiveProfile()
+	if err != nil {
+		return &authStatus{Profile: internal.ActiveProfileName(), Error: err.Error()}, err
+	}
+	status := &authStatus{Profile: profile.Name, BaseURL: profile.BaseURL}
+	fail := func(err error) (*authStatus, error) {
+		status.Error = err.Error()
+		return status, err
+	}
+
+	token, source, err := internal.LoadAPIKey(profile)
+	if err != nil {
+		return fail(err)
+	}
+	if token == "" {
+		return fail(fmt.Errorf("%w: not logged in. Run 'snippetkit login' to authenticate", internal.ErrUnauthorized))
+	}
+	status.TokenSource = source
+	status.Token = internal.MaskSecret(token)
+
+	if internal.Offline() {
+		status.Authenticated = true
+		return status, nil
+	}
+
+	checkSpinner := internal.NewSpinner()
+	checkSpinner.Start("Checking auth status...")
+	info, err := internal.InspectToken(token)
+	if err != nil {
+		var netErr *internal.NetworkError
+		if errors.As(err, &netErr) {
+			checkSpinner.Error("Couldn't reach the API")
+		} else {
+			checkSpinner.Error("Token is invalid or expired")
+			err = fmt.Errorf("%w: API token is invalid or expired. Run 'snippetkit login' to authenticate", internal.ErrUnauthorized)
+		}
+		return fail(err)
+	}
+	checkSpinner.Success("Token verified")
+
+	status.Authenticated, status.Verified = true, true
+	status.Account, status.Email = info.Name, info.Email
+	status.ExpiresAt, status.Scopes = info.ExpiresAt, info.Scopes
+	return status, nil
+}
+
+// printAuthStatus prints the status for people
+func printAuthStatus(status *authStatus) {
+	row := func(label, value string) {
+		fmt.Fprintln(internal.Stdout, ui.Label.Render(fmt.Sprintf("%-9s", label+":"))+" "+value)
+	}
+
+	row("Profile", status.Profile)
+	if status.BaseURL != "" {
+		row("API", ui.URL.Render(status.BaseURL))
+	}
+	if status.Token != "" {
+		row("Token", fmt.Sprintf("%s (from %s)", status.Token, internal.TokenSourceDescription(status.TokenSource)))
+	}
+	if !status.Authenticated {
+		row("Status", ui.Error.Render(status.Error))
+		return
+	}
+
+	account := status.Account
+	if status.Email != "" {
+		if account != "" {
+			account += " <" + status.Email + ">"
+		} else {
+			account = status.Email
+		}
+	}
+	if account == "" {
+		account = ui.Info.Render("not reported by the server")
+	}
+	row("Account", account)
+
+	switch {
+	case !status.Verified:
+		row("Status", ui.Warning.Render("not verified (offline)"))
+		return
+	case status.ExpiresAt == nil:
+		row("Expires", ui.Info.Render("never or not reported"))
+	case status.ExpiresAt.Before(time.Now()):
+		row("Expires", ui.Error.Render("expired "+status.ExpiresAt.Local().Format(time.RFC1123)))
+	default:
+		row("Expires", fmt.Sprintf("%s (in %s)", status.ExpiresAt.Local().Format(time.RFC1123), humanDuration(time.Until(*status.ExpiresAt))))
+	}
+	if len(status.Scopes) > 0 {
+		row("Scopes", strings.Join(status.Scopes, ", "))
+	}
+	row("Status", ui.Success.Render("logged in"))
+}
+
+// humanDuration formats a duration in its largest unit
+func humanDuration(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%d days", int(d.Hours()/24))
+	case d >= 2*time.Hour:
+		return fmt.Sprintf("%d hours", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%d minutes", int(d.Minutes()))
+	}
+}
@@ -0,0 +1,550 @@
+package cmd
+
+import (
+	"fmt"
+	"snippetkit/internal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// browseDebounce is how long typing has to pause before the API is queried
+const browseDebounce = 300 * time.Millisecond
+
+// browseCmd represents the browse command
+var browseCmd = &cobra.Command{
+	Use:   "browse [query]",
+	Short: "Interactively search snippets with a live preview",
+	Long: `Open a full-screen snippet browser. Type to search, tab to move between the
+query and the results, ctrl+l / ctrl+t to cycle language and tag filters,
+enter to install the selected snippet and y to copy its ID.`,
+	Args: usageArgs(cobra.MaximumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query := ""
+		if len(args) > 0 {
+			query = args[0]
+		}
+		return runBrowser(query)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(browseCmd)
+}
+
+// runBrowser starts the interactive browser and installs the snippet picked
+// by the user, if any
+func runBrowser(query string) error {
+	if !internal.CanPrompt() || output.machine() {
+		return exitErrorf(ExitUsage, "interactive search needs a terminal; use 'snippetkit search <query>' instead")
+	}
+
+	apiToken, err := requireAPIKey()
+	if err != nil {
+		return err
+	}
+
+	model := newBrowserModel(query, apiToken)
+	final, err := tea.NewProgram(model, tea.WithAltScreen()).Run()
+	if err != nil {
+		return fmt.Errorf("interactive search failed: %v", err)
+	}
+
+	picked, err := final.(browserModel).pickedSnippet()
+	if err != nil || picked == nil {
+		return err
+	}
+	return installSnippet(picked)
+}
+
+// pickedSnippet returns the snippet picked with enter, with its code. Search
+// results may leave the code out, and enter can be pressed before the preview
+// has fetched it.
+func (m browserModel) pickedSnippet() (*internal.Snippet, error) {
+	if m.install == nil {
+		return nil, nil
+	}
+	if full := m.full[m.install.ShortID]; full != nil {
+		return full, nil
+	}
+	shortID := m.install.ShortID
+	myspinner := internal.NewSpinner()
+	myspinner.Start("Fetching snippet...")
+	snippet, err := internal.FetchSnippet(shortID, m.apiToken)
+	if err != nil {
+		myspinner.Error(fmt.Sprintf("Failed to fetch snippet with ID: %s", shortID))
+		internal.Error("Failed to fetch snippet", err, nil)
+		return nil, fmt.Errorf("failed to fetch snippet %s: %w", shortID, err)
+	}
+	myspinner.Success(fmt.Sprintf("Snippet %s fetched successfully", shortID))
+	return snippet, nil
+}
+
+// Messages used by the browser
+type (
+	// browseDebounceMsg fires when typing pauses
+	browseDebounceMsg struct{ seq int }
+	// browseResultsMsg carries the results of a search
+	browseResultsMsg struct {
+		seq      int
+		page     int
+		snippets []internal.Snippet
+		hasMore  bool
+		err      error
+		// Languages and tags of the results without the ctrl+l / ctrl+t
+		// filters, sent with the first page
+		languages []string
+		tags      []string
+	}
+	// browseSnippetMsg carries a snippet fetched for the preview
+	browseSnippetMsg struct {
+		shortID string
+		snippet *internal.Snippet
+		err     error
+	}
+)
+
+// browserModel is the bubbletea model of the snippet browser
+type browserModel struct {
+	apiToken string
+	input    textinput.Model
+	preview  viewport.Model
+	width    int
+	height   int
+
+	seq      int // Sequence of the latest search, older results are dropped
+	loading  bool
+	err      error
+	status   string
+	snippets []internal.Snippet
+	page     int  // Pages of results loaded so far
+	hasMore  bool // More results can be fetched
+	cursor   int
+	listMode bool // Keys go to the result list instead of the query input
+
+	lang       string
+	tag        string
+	langValues []string // Languages the lang filter cycles through
+	tagValues  []string // Tags the tag filter cycles through
+
+	full    map[string]*internal.Snippet // Snippets with code, by short ID
+	install *internal.Snippet
+}
+
+func newBrowserModel(query, apiToken string) browserModel {
+	input := textinput.New()
+	input.Placeholder = "Search snippets..."
+	input.Prompt = "> "
+	input.SetValue(query)
+	input.Focus()
+
+	return browserModel{
+		apiToken: apiToken,
+		input:    input,
+		preview:  viewport.New(0, 0),
+		lang:     langFilter,
+		tag:      tagFilter,
+		full:     map[string]*internal.Snippet{},
+	}
+}
+
+func (m browserModel) Init() tea.Cmd {
+	if m.input.Value() == "" {
+		return textinput.Blink
+	}
+	// Search right away for the query given on the command line
+	seq := m.seq
+	return tea.Batch(textinput.Blink, func() tea.Msg {
+		return browseDebounceMsg{seq: seq}
+	})
+}
+
+// debounce schedules a search once typing pauses
+func (m *browserModel) debounce() tea.Cmd {
+	m.seq++
+	seq := m.seq
+	return tea.Tick(browseDebounce, func(time.Time) tea.Msg {
+		return browseDebounceMsg{seq: seq}
+	})
+}
+
+// search queries the API for a page of results in the background
+func (m *browserModel) search(page int) tea.Cmd {
+	m.loading = true
+	seq, query, lang, tag, token := m.seq, m.input.Value(), m.lang, m.tag, m.apiToken
+	return func() tea.Msg {
+		q, err := internal.ParseQuery(query)
+		if err != nil {
+			return browseResultsMsg{seq: seq, err: err}
+		}
+		q.AddLang(lang)
+		q.AddTag(tag)
+		result, err := internal.SearchRemote(q, page, limit, token)
+		if err != nil {
+			return browseResultsMsg{seq: seq, page: page, err: err}
+		}
+		msg := browseResultsMsg{seq: seq, page: page, snippets: result.Snippets, hasMore: result.HasMore}
+		if page == 1 {
+			// The filters cycle through the values of the unfiltered results;
+			// the filtered ones would only offer the value already picked
+			unfiltered := result.Snippets
+			if lang != "" || tag != "" {
+				if q, err := internal.ParseQuery(query); err == nil {
+					if all, err := internal.SearchRemote(q, 1, limit, token); err == nil {
+						unfiltered = all.Snippets
+					}
+				}
+			}
+			msg.languages, msg.tags = snippetLanguages(unfiltered), snippetTags(unfiltered)
+		}
+		return msg
+	}
+}
+
+// fetchSelected loads the code of the selected snippet when the search
+// results didn't include it
+func (m *browserModel) fetchSelected() tea.Cmd {
+	selected := m.selected()
+	if selected == nil || selected.Code != "" || m.full[selected.ShortID] != nil {
+		return nil
+	}
+	shortID, token := selected.ShortID, m.apiToken
+	return func() tea.Msg {
+		snippet, err := internal.FetchSnippet(shortID, token)
+		return browseSnippetMsg{shortID: shortID, snippet: snippet, err: err}
+	}
+}
+
+// selected returns the snippet under the cursor
+func (m browserModel) selected() *internal.Snippet {
+	if m.cursor < 0 || m.cursor >= len(m.snippets) {
+		return nil
+	}
+	snippet := &m.snippets[m.cursor]
+	if full := m.full[snippet.ShortID]; full != nil {
+		return full
+	}
+	return snippet
+}
+
+func (m browserModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.resize()
+		return m, nil
+
+	case browseDebounceMsg:
+		if msg.seq != m.seq {
+			return m, nil
+		}
+		if strings.TrimSpace(m.input.Value()) == "" {
+			m.snippets, m.loading = nil, false
+			m.langValues, m.tagValues = nil, nil
+			m.refreshPreview()
+			return m, nil
+		}
+		return m, m.search(1)
+
+	case browseResultsMsg:
+		if msg.seq != m.seq {
+			return m, nil // Stale results
+		}
+		m.loading = false
+		m.err = msg.err
+		if msg.err != nil {
+			return m, nil
+		}
+		m.page, m.hasMore = msg.page, msg.hasMore
+		if msg.page > 1 {
+			// Another page of the same search, keep the cursor where it is
+			m.snippets = append(m.snippets, msg.snippets...)
+			if m.lang == "" && m.tag == "" {
+				m.langValues, m.tagValues = snippetLanguages(m.snippets), snippetTags(m.snippets)
+			}
+			return m, nil
+		}
+		m.snippets = msg.snippets
+		m.langValues, m.tagValues = msg.languages, msg.tags
+		m.cursor = 0
+		m.refreshPreview()
+		return m, m.fetchSelected()
+
+	case browseSnippetMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Failed to load %s: %v", msg.shortID, msg.err)
+			return m, nil
+		}
+		m.full[msg.shortID] = msg.snippet
+		m.refreshPreview()
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m browserModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "esc":
+		return m, tea.Quit
+	case "tab", "shift+tab":
+		m.listMode = !m.listMode
+		if m.listMode {
+			m.input.Blur()
+			return m, nil
+		}
+		return m, m.input.Focus()
+	case "ctrl+l":
+		m.lang = cycleFilter(m.lang, m.langValues)
+		return m, m.debounce()
+	case "ctrl+t":
+		m.tag = cycleFilter(m.tag, m.tagValues)
+		return m, m.debounce()
+	case "up", "ctrl+p":
+		return m.moveCursor(-1)
+	case "down", "ctrl+n":
+		return m.moveCursor(1)
+	case "pgup":
+		m.preview.HalfViewUp()
+		return m, nil
+	case "pgdown":
+		m.preview.HalfViewDown()
+		return m, nil
+	case "enter":
+		if selected := m.selected(); selected != nil {
+			m.install = selected
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+
+	if m.listMode {
+		switch msg.String() {
+		case "k":
+			return m.moveCursor(-1)
+		case "j":
+			return m.moveCursor(1)
+		case "y":
+			if selected := m.selected(); selected != nil {
+				if err := clipboard.WriteAll(selected.ShortID); err != nil {
+					m.status = fmt.Sprintf("Failed to copy ID: %v", err)
+				} else {
+					m.status = fmt.Sprintf("Copied %s to the clipboard", selected.ShortID)
+				}
+			}
+			return m, nil
+		}
+		return m, nil
+	}
+
+	// Anything else edits the query
+	before := m.input.Value()
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	if m.input.Value() != before {
+		return m, tea.Batch(cmd, m.debounce())
+	}
+	return m, cmd
+}
+
+func (m browserModel) moveCursor(delta int) (tea.Model, tea.Cmd) {
+	if len(m.snippets) == 0 {
+		return m, nil
+	}
+	if delta > 0 && m.cursor == len(m.snippets)-1 && m.hasMore {
+		// Fetch the next page instead of wrapping around
+		if m.loading {
+			return m, nil
+		}
+		return m, m.search(m.page + 1)
+	}
+	m.cursor = (m.cursor + delta + len(m.snippets)) % len(m.snippets)
+	m.status = ""
+	m.refreshPreview()
+	return m, m.fetchSelected()
+}
+
+// snippetLanguages returns the languages of snippets
+func snippetLanguages(snippets []internal.Snippet) []string {
+	seen := map[string]bool{}
+	for _, snippet := range snippets {
+		if snippet.Language != "" {
+			seen[snippet.Language] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// snippetTags returns the tags of snippets
+func snippetTags(snippets []internal.Snippet) []string {
+	seen := map[string]bool{}
+	for _, snippet := range snippets {
+		for _, tag := range snippet.Tags {
+			seen[tag] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// cycleFilter moves a filter to the next value, wrapping back to no filter
+func cycleFilter(current string, values []string) string {
+	if current != "" && !containsString(values, current) {
+		values = append([]string{current}, values...)
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	if current == "" {
+		return values[0]
+	}
+	for i, value := range values {
+		if value == current && i+1 < len(values) {
+			return values[i+1]
+		}
+	}
+	return ""
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// listWidth is the width of the result list column
+func (m browserModel) listWidth() int {
+	return m.width * 2 / 5
+}
+
+func (m *browserModel) resize() {
+	m.input.Width = m.width - 4
+	m.preview.Width = m.width - m.listWidth() - 3
+	m.preview.Height = m.height - 5
+	m.refreshPreview()
+}
+
+// refreshPreview renders the selected snippet into the preview pane
+func (m *browserModel) refreshPreview() {
+	selected := m.selected()
+	if selected == nil {
+		m.preview.SetContent("")
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(ui.Title.Render(selected.Title) + "\n")
+	if selected.Description != "" {
+		b.WriteString(ui.Info.Render(selected.Description) + "\n")
+	}
+	b.WriteString(ui.Label.Render("Language: ") + selected.Language + "\n")
+	b.WriteString(ui.Label.Render("Tags: ") + strings.Join(selected.Tags, ", ") + "\n\n")
+	if selected.Code == "" {
+		b.WriteString(ui.Info.Render("Loading preview..."))
+	} else {
+		b.WriteString(highlightCode(selected.Code, selected.Language))
+	}
+	m.preview.SetContent(b.String())
+	m.preview.GotoTop()
+}
+
+func (m browserModel) View() string {
+	if m.width == 0 {
+		return ""
+	}
+
+	// Query and active filters
+	filters := "lang: " + orAll(m.lang) + "  tag: " + orAll(m.tag)
+	if m.loading {
+		filters += "  " + ui.Info.Render("searching...")
+	}
+	header := m.input.View() + "\n" + ui.Info.Render(filters)
+
+	// Result list
+	listHeight := m.height - 5
+	var list strings.Builder
+	switch {
+	case m.err != nil:
+		list.WriteString(ui.Error.Render(m.err.Error()))
+	case len(m.snippets) == 0 && strings.TrimSpace(m.input.Value()) != "" && !m.loading:
+		list.WriteString(ui.Info.Render("No snippets found"))
+	}
+	start := 0
+	if m.cursor >= listHeight {
+		start = m.cursor - listHeight + 1
+	}
+	for i := start; i < len(m.snippets) && i < start+listHeight; i++ {
+		snippet := m.snippets[i]
+		line := fmt.Sprintf("%s %s [%s]", snippet.ShortID, snippet.Title, snippet.Language)
+		line = truncate(line, m.listWidth()-2)
+		if i == m.cursor {
+			line = ui.Title.Render("▸ " + line)
+		} else {
+			line = "  " + line
+		}
+		list.WriteString(line + "\n")
+	}
+	if m.hasMore && len(m.snippets)-start <= listHeight-1 {
+		list.WriteString(ui.Info.Render("  ↓ more results") + "\n")
+	}
+	listBox := lipgloss.NewStyle().Width(m.listWidth()).Height(listHeight).Render(list.String())
+	previewBox := lipgloss.NewStyle().
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderLeft(true).
+		PaddingLeft(1).
+		Render(m.preview.View())
+	body := lipgloss.JoinHorizontal(lipgloss.Top, listBox, previewBox)
+
+	// Key help or status
+	footer := ui.Info.Render("tab: results/query • ctrl+l: lang • ctrl+t: tag • enter: install • y: copy ID • esc: quit")
+	if m.status != "" {
+		footer = ui.Success.Render(m.status)
+	}
+
+	return header + "\n" + body + "\n" + footer
+}
+
+func orAll(filter string) string {
+	if filter == "" {
+		return "all"
+	}
+	return filter
+}
+
+// truncate shortens s to at most width cells
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && lipgloss.Width(string(runes))+1 > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"snippetkit/internal"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/viper"
+)
+
+func TestBrowseEnterBeforePreviewLoads(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/snippet/get/abc" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"success":true,"data":{"shortId":"abc","title":"Retry","language":"go","code":"package retry\n"}}`)
+	}))
+	defer server.Close()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("base_url", server.URL)
+
+	// Search results without code, and enter pressed before the preview
+	// fetched the selected snippet
+	m := newBrowserModel("retry", "token")
+	m.snippets = []internal.Snippet{{ShortID: "abc", Title: "Retry", Language: "go"}}
+	final, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	picked, err := final.(browserModel).pickedSnippet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if picked == nil || picked.Code != "package retry\n" {
+		t.Fatalf("picked snippet = %+v, want the fetched code", picked)
+	}
+}
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"snippetkit/internal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Flags
+var (
+	bundleLogs     int
+	bundleRequests int
+	bundleDryRun   bool
+)
+
+// redacted replaces tokens in a debug bundle
+const redacted = "[redacted]"
+
+// debugBundleCmd collects diagnostics for a support ticket
+var debugBundleCmd = &cobra.Command{
+	Use:   "debug-bundle [file]",
+	Short: "Collect diagnostics into a tar.gz for a support ticket",
+	Long: `Collect diagnostics into a single tar.gz to attach to a support ticket:
+
+  info.txt          snippetkit version, OS and architecture, profile and paths
+  config.json       the effective settings and where they come from
+  doctor.txt        the output of 'snippetkit doctor'
+  http.json         summaries of the last API requests, without headers or bodies
+  logs/             the newest log files
+
+Tokens are redacted. The files are listed before the bundle is written, so you
+can check what will be shared; --dry-run only lists them. The bundle is written
+to snippetkit-debug-<time>.tar.gz in the current directory unless a file is
+given.`,
+	Example: `  snippetkit debug-bundle
+  snippetkit debug-bundle --dry-run
+  snippetkit debug-bundle /tmp/snippetkit.tar.gz --logs 1`,
+	Args: usageArgs(cobra.MaximumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := internal.DefaultBundlePath()
+		if len(args) == 1 {
+			path = args[0]
+		}
+		if !bundleDryRun && internal.FileExists(path) {
+			return exitErrorf(ExitConflict, "file %s already exists", path)
+		}
+
+		myspinner := internal.NewSpinner()
+		myspinner.Start("Collecting diagnostics...")
+		files, err := collectBundleFiles()
+		if err != nil {
+			myspinner.Error("Failed to collect diagnostics")
+			return err
+		}
+		myspinner.Success(fmt.Sprintf("Collected %d files", len(files)))
+
+		result := bundleResult{Path: path}
+		var total int64
+		for _, file := range files {
+			result.Files = append(result.Files, bundleFileView{Name: file.Name, Size: int64(len(file.Data))})
+			total += int64(len(file.Data))
+		}
+
+		// The preview goes to stderr so stdout stays machine readable
+		statusln(ui.Title.Render("The debug bundle will contain:"))
+		for _, file := range result.Files {
+			statusln(fmt.Sprintf("  %-36s %10s", file.Name, internal.FormatSize(file.Size)))
+		}
+		statusln(ui.Info.Render(fmt.Sprintf("  %d files, %s before compression", len(result.Files), internal.FormatSize(total))))
+
+		if !bundleDryRun && internal.YesNoPrompt("Write the debug bundle to "+path+"?", true) {
+			if err := internal.WriteBundle(path, files); err != nil {
+				return err
+			}
+			result.Written = true
+			internal.Info("Wrote debug bundle", map[string]interface{}{"path": path})
+		}
+
+		if output.machine() {
+			return writeResult(os.Stdout, schemaBundle, result)
+		}
+		if result.Written {
+			statusln(ui.Success.Render("Debug bundle written to " + path))
+			statusln(ui.Info.Render("Check its contents before sharing it: tar -tzf " + path))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(debugBundleCmd)
+	debugBundleCmd.Flags().IntVar(&bundleLogs, "logs", 3, "Number of recent log files to include")
+	debugBundleCmd.Flags().IntVar(&bundleRequests, "requests", 20, "Number of recent API requests to include")
+	debugBundleCmd.Flags().BoolVar(&bundleDryRun, "dry-run", false, "List what would be included without writing the bundle")
+}
+
+// bundleResult is the machine readable result of debug-bundle
+type bundleResult struct {
+	Path    string           `json:"path" yaml:"path"`
+	Written bool             `json:"written" yaml:"written"`
+	Files   []bundleFileView `json:"files" yaml:"files"`
+}
+
+func (r bundleResult) items() []interface{} {
+	values := make([]interface{}, len(r.Files))
+	for i, file := range r.Files {
+		values[i] = file
+	}
+	return values
+}
+
+type bundleFileView struct {
+	Name string `json:"name" yaml:"name"`
+	Size int64  `json:"size" yaml:"size"`
+}
+
+// collectBundleFiles gathers the contents of a debug bundle, with the known
+// tokens redacted
+func collectBundleFiles() ([]internal.BundleFile, error) {
+	files := []internal.BundleFile{{Name: "info.txt", Data: bundleInfo()}}
+
+	listing := configListing{File: internal.ConfigFile(), ProjectFile: internal.ProjectConfigFile()}
+	for _, value := range internal.ConfiguredSettings() {
+		if !value.IsSet {
+			continue
+		}
+		view := newConfigValueView(value, false)
+		if view.Secret && view.Value != "" {
+			view.Value = redacted
+		}
+		listing.Settings = append(listing.Settings, view)
+	}
+	config, err := bundleJSON(listing)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, internal.BundleFile{Name: "config.json", Data: config})
+
+	var doctor bytes.Buffer
+	printDoctorReport(&doctor, newDoctorReport(internal.RunChecks()), false)
+	files = append(files, internal.BundleFile{Name: "doctor.txt", Data: doctor.Bytes()})
+
+	requests, err := bundleJSON(internal.HTTPHistory(bundleRequests))
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, internal.BundleFile{Name: "http.json", Data: requests})
+
+	logs, err := internal.RecentLogFiles(bundleLogs)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, logs...)
+
+	redactBundle(files, bundleSecrets())
+	return files, nil
+}
+
+// redactBundle replaces the secrets wherever they show up in files
+func redactBundle(files []internal.BundleFile, secrets []string) {
+	for i := range files {
+		for _, secret := range secrets {
+			files[i].Data = bytes.ReplaceAll(files[i].Data, []byte(secret), []byte(redacted))
+		}
+	}
+}
+
+// bundleJSON formats a file of the bundle. URLs are kept readable.
+func bundleJSON(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// bundleInfo describes the installation
+func bundleInfo() []byte {
+	var info strings.Builder
+	fmt.Fprintf(&info, "snippetkit %s\n", internal.GetVersion())
+	fmt.Fprintf(&info, "go:             %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&info, "created:        %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&info, "profile:        %s\n", internal.ActiveProfileName())
+	fmt.Fprintf(&info, "base url:       %s\n", internal.BaseURL())
+	fmt.Fprintf(&info, "config file:    %s\n", internal.ConfigFile())
+	fmt.Fprintf(&info, "project config: %s\n", internal.ProjectConfigFile())
+	fmt.Fprintf(&info, "cache dir:      %s\n", internal.CacheDir())
+	fmt.Fprintf(&info, "log dir:        %s\n", internal.LogDir())
+	return []byte(info.String())
+}
+
+// bundleSecrets returns the tokens that can be read without a passphrase or
+// credential helper, so they can be redacted wherever they show up. Values
+// too short to be tokens are left alone.
+func bundleSecrets() []string {
+	var secrets []string
+	if token := os.Getenv(internal.EnvVar("api_key")); len(token) >= 8 {
+		secrets = append(secrets, token)
+	}
+	for _, value := range internal.ConfiguredSettings() {
+		if value.Secret && len(value.Value) >= 8 {
+			secrets = append(secrets, value.Value)
+		}
+	}
+	return secrets
+}
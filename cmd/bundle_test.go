@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"snippetkit/internal"
+
+	"github.com/spf13/viper"
+)
+
+func TestBundleSecretsAreRedacted(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Setenv(internal.EnvVar("api_key"), "env-token-123456")
+	viper.Set("api_key", "default-token-abcdef")
+	viper.Set("profiles.work.api_key", "work-token-987654")
+	viper.Set("profiles.home.api_key", "short")
+	viper.Set("base_url", "https://snippets.example.com")
+
+	secrets := bundleSecrets()
+	sort.Strings(secrets)
+	want := []string{"default-token-abcdef", "env-token-123456", "work-token-987654"}
+	if !reflect.DeepEqual(secrets, want) {
+		t.Errorf("bundleSecrets() = %q, want %q", secrets, want)
+	}
+
+	files := []internal.BundleFile{
+		{Name: "logs/snippetkit-2026-01-01.log", Data: []byte("GET /snippets Authorization: Bearer work-token-987654\n")},
+		{Name: "config.json", Data: []byte(`{"api_key": "env-token-123456", "base_url": "https://snippets.example.com"}`)},
+	}
+	redactBundle(files, secrets)
+	for _, file := range files {
+		for _, secret := range secrets {
+			if bytes.Contains(file.Data, []byte(secret)) {
+				t.Errorf("%s still holds %q: %s", file.Name, secret, file.Data)
+			}
+		}
+		if !bytes.Contains(file.Data, []byte(redacted)) {
+			t.Errorf("%s wasn't redacted: %s", file.Name, file.Data)
+		}
+	}
+	if !bytes.Contains(files[1].Data, []byte("https://snippets.example.com")) {
+		t.Error("redaction removed the base URL")
+	}
+}
+
+func TestBundleHTTPHistoryHidesFailedQueries(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close() // Requests to it fail with a *url.Error
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("base_url", server.URL)
+	viper.Set("logging_enabled", true)
+
+	if _, err := internal.SearchSnippets(internal.SearchOptions{Query: "acme merger plans", Limit: 10}, "token"); err == nil {
+		t.Fatal("search against a closed server succeeded")
+	}
+	history := internal.HTTPHistory(bundleRequests)
+	if len(history) != 1 || history[0].Error == "" {
+		t.Fatalf("HTTP history = %+v, want one failed request", history)
+	}
+	data, err := bundleJSON(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, term := range []string{"acme", "merger", "plans"} {
+		if bytes.Contains(data, []byte(term)) {
+			t.Errorf("http.json holds the search term %q: %s", term, data)
+		}
+	}
+}
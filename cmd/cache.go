@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"snippetkit/internal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var cachePruneAge time.Duration
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and manage the local snippet cache",
+	Long: `Snippets and search results fetched from the API are cached on disk so they
+can be reused offline with --offline. The cache is revalidated after cache.ttl
+and trimmed to cache.max_size by evicting the least recently used entries.`,
+}
+
+// cacheLsCmd lists the cached responses
+var cacheLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List cached snippets and searches",
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache, err := internal.OpenCache()
+		if err != nil {
+			return err
+		}
+
+		entries := cache.Entries()
+		if output.machine() {
+			result := cacheListing{Count: len(entries), Size: cache.Size(), Entries: make([]cacheEntryView, 0, len(entries))}
+			for _, entry := range entries {
+				result.Entries = append(result.Entries, newCacheEntryView(entry))
+			}
+			return writeResult(os.Stdout, schemaCache, result)
+		}
+
+		if len(entries) == 0 {
+			statusln(ui.Info.Render("The cache is empty."))
+			return nil
+		}
+
+		ttl := internal.CacheTTL()
+		fmt.Fprintln(internal.Stdout, ui.Title.Render("> Cached Entries:"))
+		for _, entry := range entries {
+			state := ui.Success.Render("fresh")
+			if !entry.Fresh(ttl) {
+				state = ui.Warning.Render("stale")
+			}
+			fmt.Fprintf(internal.Stdout, "* %s  %s  %s  %s\n",
+				ui.Label.Render(entry.Key),
+				entry.Label,
+				ui.Info.Render(internal.FormatSize(entry.Size)),
+				state,
+			)
+		}
+		return nil
+	},
+}
+
+// cachePruneCmd removes stale entries
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove stale cache entries and unreferenced objects",
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache, err := internal.OpenCache()
+		if err != nil {
+			return err
+		}
+
+		maxAge := cachePruneAge
+		if maxAge == 0 {
+			maxAge = internal.CacheTTL()
+		}
+		removed, err := cache.Prune(maxAge)
+		if err != nil {
+			return err
+		}
+		internal.Info("Pruned cache", map[string]interface{}{"removed": removed})
+		statusln(ui.Success.Render(fmt.Sprintf("Removed %d stale cache entries", removed)))
+		return nil
+	},
+}
+
+// cacheClearCmd empties the cache
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove everything from the cache",
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache, err := internal.OpenCache()
+		if err != nil {
+			return err
+		}
+		if err := cache.Clear(); err != nil {
+			return err
+		}
+		internal.Info("Cleared cache", nil)
+		statusln(ui.Success.Render("Cache cleared"))
+		return nil
+	},
+}
+
+// cacheSizeCmd shows how much space the cache uses
+var cacheSizeCmd = &cobra.Command{
+	Use:   "size",
+	Short: "Show the size of the cache",
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache, err := internal.OpenCache()
+		if err != nil {
+			return err
+		}
+
+		size := cache.Size()
+		if output.machine() {
+			return writeResult(os.Stdout, schemaCache, cacheListing{Count: len(cache.Entries()), Size: size})
+		}
+		fmt.Fprintf(internal.Stdout, "%s %s of %s (%d entries)\n",
+			ui.Label.Render("Cache size:"),
+			internal.FormatSize(size),
+			internal.FormatSize(internal.CacheMaxSize()),
+			len(cache.Entries()),
+		)
+		fmt.Fprintln(internal.Stdout, ui.Label.Render("Location: ")+internal.CacheDir())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheLsCmd, cachePruneCmd, cacheClearCmd, cacheSizeCmd)
+	cachePruneCmd.Flags().DurationVar(&cachePruneAge, "older-than", 0, "Remove entries fetched longer ago than this (default cache.ttl)")
+}
+
+// cacheListing is the machine readable result of the cache commands
+type cacheListing struct {
+	Count   int              `json:"count" yaml:"count"`
+	Size    int64            `json:"size" yaml:"size"`
+	Entries []cacheEntryView `json:"entries,omitempty" yaml:"entries,omitempty"`
+}
+
+func (l cacheListing) items() []interface{} {
+	values := make([]interface{}, len(l.Entries))
+	for i, entry := range l.Entries {
+		values[i] = entry
+	}
+	return values
+}
+
+// cacheEntryView is the stable representation of a cache entry
+type cacheEntryView struct {
+	Key        string    `json:"key" yaml:"key"`
+	Kind       string    `json:"kind" yaml:"kind"`
+	Label      string    `json:"label" yaml:"label"`
+	Size       int64     `json:"size" yaml:"size"`
+	Fresh      bool      `json:"fresh" yaml:"fresh"`
+	FetchedAt  time.Time `json:"fetched_at" yaml:"fetched_at"`
+	AccessedAt time.Time `json:"accessed_at" yaml:"accessed_at"`
+}
+
+func newCacheEntryView(entry internal.CacheEntry) cacheEntryView {
+	kind, _, _ := strings.Cut(entry.Key, ":")
+	return cacheEntryView{
+		Key:        entry.Key,
+		Kind:       kind,
+		Label:      entry.Label,
+		Size:       entry.Size,
+		Fresh:      entry.Fresh(internal.CacheTTL()),
+		FetchedAt:  entry.FetchedAt,
+		AccessedAt: entry.AccessedAt,
+	}
+}
@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"snippetkit/internal"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Flags
+var (
+	configReveal     bool
+	configAll        bool
+	configShowOrigin bool
+)
+
+// configCmd groups the config commands
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and change settings in the config file",
+	Long: `Read and change the settings of the config file. Keys are checked against
+the known settings and values are validated before they are written.
+
+Settings named after a command set the defaults of its flags, e.g. search.limit
+or add.path. Flags given on the command line take precedence.`,
+	Example: `  snippetkit config set search.limit 25
+  snippetkit config get cache.ttl
+  snippetkit config list --all`,
+}
+
+// configGetCmd prints a setting
+var configGetCmd = &cobra.Command{
+	Use:   "get [key]",
+	Short: "Print the value of a setting",
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, err := internal.GetSetting(args[0])
+		if err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		view := newConfigValueView(*value, configReveal)
+		if output.machine() {
+			return writeResult(os.Stdout, schemaConfig, view)
+		}
+		fmt.Fprintln(internal.Stdout, view.Value)
+		return nil
+	},
+}
+
+// configSetCmd changes a setting
+var configSetCmd = &cobra.Command{
+	Use:   "set [key] [value]",
+	Short: "Change a setting",
+	Args:  usageArgs(cobra.ExactArgs(2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := strings.ToLower(args[0])
+		setting, err := internal.LookupSetting(key)
+		if err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		if _, err := setting.Parse(args[1]); err != nil {
+			return exitErrorf(ExitUsage, "invalid value for %s: %v", key, err)
+		}
+		if err := internal.SetSetting(key, args[1]); err != nil {
+			return err
+		}
+
+		shown := args[1]
+		if setting.Secret {
+			shown = internal.MaskSecret(shown)
+		}
+		internal.Info("Changed setting", map[string]interface{}{"key": key})
+		statusln(ui.Success.Render(fmt.Sprintf("Set %s to %s", key, shown)))
+		if origin, source := internal.SettingOrigin(key); origin != internal.OriginUser {
+			statusln(ui.Warning.Render(fmt.Sprintf("Note: %s is overridden by %s %s", key, origin, source)))
+		}
+		return nil
+	},
+}
+
+// configUnsetCmd removes a setting
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset [key]",
+	Short: "Remove a setting, restoring its default",
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := strings.ToLower(args[0])
+		removed, err := internal.UnsetSetting(key)
+		if err != nil {
+			if errors.Is(err, internal.ErrUnknownSetting) {
+				return withExitCode(ExitUsage, err)
+			}
+			return err
+		}
+		if !removed {
+			statusln(ui.Info.Render(fmt.Sprintf("%s is not set", key)))
+			return nil
+		}
+		internal.Info("Removed setting", map[string]interface{}{"key": key})
+		statusln(ui.Success.Render(fmt.Sprintf("Unset %s", key)))
+		return nil
+	},
+}
+
+// configListCmd lists the settings
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the settings",
+	Long: `List the settings in effect. With --all every known setting is listed with its
+default and description. Secrets are masked unless --reveal is set.
+
+Settings are resolved in this order, and --show-origin shows where each value
+comes from:
+
+  1. flags, e.g. --no-color
+  2. environment variables, e.g. SNIPPETKIT_LOG_LEVEL for log_level
+  3. the project config, .snippetkit.yaml in the current directory or a parent
+  4. the user config ('snippetkit config path')
+  5. defaults
+
+A project config may only set command defaults (search.*, add.*, info.*),
+theme, ui_theme and preview_lines. Settings that choose where tokens are sent
+or which programs run, like base_url, profiles and credential_helper, are
+ignored there with a warning.`,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		listing := configListing{File: internal.ConfigFile(), ProjectFile: internal.ProjectConfigFile()}
+		for _, value := range internal.ConfiguredSettings() {
+			if !value.IsSet && !configAll {
+				continue
+			}
+			listing.Settings = append(listing.Settings, newConfigValueView(value, configReveal))
+		}
+		if output.machine() {
+			return writeResult(os.Stdout, schemaConfig, listing)
+		}
+
+		if len(listing.Settings) == 0 {
+			statusln(ui.Info.Render("No settings in " + listing.File + ". Run 'snippetkit config list --all' to see them all."))
+			return nil
+		}
+		for _, value := range listing.Settings {
+			line := ui.Label.Render(value.Key) + " = " + value.Value
+			if configShowOrigin {
+				origin := value.Origin
+				if value.Source != "" {
+					origin += ":" + value.Source
+				}
+				line = ui.Info.Render(fmt.Sprintf("%-40s", origin)) + " " + line
+			} else if !value.Set {
+				line += ui.Info.Render(" (default)")
+			}
+			fmt.Fprintln(internal.Stdout, line)
+			if configAll {
+				fmt.Fprintln(internal.Stdout, "  "+ui.Info.Render(value.Description))
+			}
+		}
+		return nil
+	},
+}
+
+// configEditCmd opens the config file in an editor
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the config file in your editor",
+	Long:  "Open the config file in $VISUAL or $EDITOR (default vi) and check it afterwards.",
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !internal.CanPrompt() {
+			return exitErrorf(ExitUsage, "config edit needs a terminal. Use 'snippetkit config set' instead")
+		}
+		path := internal.ConfigFile()
+		if !internal.FileExists(path) {
+			if err := internal.EnsureDirExists(path); err != nil {
+				return fmt.Errorf("failed to create config directory: %v", err)
+			}
+			if err := os.WriteFile(path, nil, 0600); err != nil {
+				return fmt.Errorf("failed to create config file: %v", err)
+			}
+		}
+
+		editor := os.Getenv("VISUAL")
+		if editor == "" {
+			editor = os.Getenv("EDITOR")
+		}
+		if editor == "" {
+			editor = "vi"
+		}
+		fields := strings.Fields(editor)
+		edit := exec.Command(fields[0], append(fields[1:], path)...)
+		edit.Stdin, edit.Stdout, edit.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := edit.Run(); err != nil {
+			return fmt.Errorf("failed to run editor %s: %v", editor, err)
+		}
+
+		problems, err := internal.ValidateConfigFile()
+		if err != nil {
+			return err
+		}
+		for _, problem := range problems {
+			statusln(ui.Warning.Render("Warning: " + problem.Error()))
+		}
+		if len(problems) == 0 {
+			statusln(ui.Success.Render("Config file is valid"))
+		}
+		return nil
+	},
+}
+
+// configPathCmd prints the location of the config file
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the location of the config file",
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fmt.Fprintln(internal.Stdout, internal.ConfigFile())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd, configSetCmd, configUnsetCmd, configListCmd, configEditCmd, configPathCmd)
+	configGetCmd.Flags().BoolVar(&configReveal, "reveal", false, "Show secrets instead of masking them")
+	configListCmd.Flags().BoolVar(&configReveal, "reveal", false, "Show secrets instead of masking them")
+	configListCmd.Flags().BoolVarP(&configAll, "all", "a", false, "Include settings left at their default")
+	configListCmd.Flags().BoolVar(&configShowOrigin, "show-origin", false, "Show where each value comes from")
+}
+
+// applyCommandDefaults sets the running command's flags from its settings,
+// e.g. search.limit, where they weren't given
+func applyCommandDefaults(cmd *cobra.Command) error {
+	return applyFlagDefaults(cmd, internal.CommandDefaults(cmd.Name()), "the config file")
+}
+
+// applyFlagDefaults sets flags that weren't given on the command line. The
+// flags aren't marked as changed, so later defaults still override them.
+func applyFlagDefaults(cmd *cobra.Command, defaults map[string]string, source string) error {
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		flag := cmd.Flags().Lookup(name)
+		// Global flags are left to the config file
+		if flag == nil || flag.Changed || cmd.Root().PersistentFlags().Lookup(name) != nil {
+			continue
+		}
+		if err := flag.Value.Set(defaults[name]); err != nil {
+			return exitErrorf(ExitUsage, "invalid default %s=%q in %s: %v", name, defaults[name], source, err)
+		}
+	}
+	return nil
+}
+
+// configListing is the machine readable result of config list
+type configListing struct {
+	File        string            `json:"file" yaml:"file"`
+	ProjectFile string            `json:"project_file,omitempty" yaml:"project_file,omitempty"`
+	Settings    []configValueView `json:"settings" yaml:"settings"`
+}
+
+func (l configListing) items() []interface{} {
+	values := make([]interface{}, len(l.Settings))
+	for i, value := range l.Settings {
+		values[i] = value
+	}
+	return values
+}
+
+// configValueView is the stable representation of a setting
+type configValueView struct {
+	Key         string `json:"key" yaml:"key"`
+	Value       string `json:"value" yaml:"value"`
+	Set         bool   `json:"set" yaml:"set"`
+	Origin      string `json:"origin" yaml:"origin"` // flag, env, project, user or default
+	Source      string `json:"source,omitempty" yaml:"source,omitempty"`
+	Type        string `json:"type" yaml:"type"`
+	Default     string `json:"default,omitempty" yaml:"default,omitempty"`
+	Description string `json:"description" yaml:"description"`
+	Secret      bool   `json:"secret,omitempty" yaml:"secret,omitempty"`
+}
+
+func newConfigValueView(value internal.SettingValue, reveal bool) configValueView {
+	shown := value.Value
+	if value.Secret && !reveal {
+		shown = internal.MaskSecret(shown)
+	}
+	return configValueView{
+		Key:         value.Key,
+		Value:       shown,
+		Set:         value.IsSet,
+		Origin:      value.Origin,
+		Source:      value.Source,
+		Type:        value.Type,
+		Default:     value.Default,
+		Description: value.Description,
+		Secret:      value.Secret,
+	}
+}
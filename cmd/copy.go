@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"snippetkit/internal"
+
+	"github.com/spf13/cobra"
+)
+
+// largeSnippetSize is the size above which copying asks for confirmation
+const largeSnippetSize = 256 << 10
+
+// Flags
+var (
+	copyVars  []string
+	copyOSC52 bool
+)
+
+// copyCmd puts the code of a snippet on the clipboard
+var copyCmd = &cobra.Command{
+	Use:   "copy [snippet ID]",
+	Short: "Copy a snippet's code to the clipboard",
+	Long: `Copy the code of a snippet to the clipboard. The system clipboard is used when
+available; over SSH, or with --osc52, the terminal's clipboard is set through
+an OSC 52 escape sequence instead (tmux needs 'set -g set-clipboard on').
+
+Template variables in the code, written {{var:name}} or {{var:name|default}},
+are filled in first from --var, their defaults or a prompt.`,
+	Example: `  snippetkit copy abc123
+  snippetkit copy abc123 --var name=Button --var size=lg`,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snippetID := args[0]
+
+		apiToken, err := requireAPIKey()
+		if err != nil {
+			return err
+		}
+
+		myspinner := internal.NewSpinner()
+		myspinner.Start(fmt.Sprintf("Fetching snippet %s...", snippetID))
+		snippet, err := internal.FetchSnippet(snippetID, apiToken)
+		if err != nil {
+			myspinner.Error(fmt.Sprintf("Failed to fetch snippet with ID: %s", snippetID))
+			internal.Error("Failed to fetch snippet", err, nil)
+			return fmt.Errorf("failed to fetch snippet %s: %w", snippetID, err)
+		}
+		myspinner.Success(fmt.Sprintf("Snippet %s fetched successfully", snippetID))
+
+		result, err := copySnippet(snippet)
+		if err != nil {
+			return err
+		}
+		if output.machine() {
+			return writeResult(os.Stdout, schemaCopy, result)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(copyCmd)
+	copyCmd.Flags().StringArrayVar(&copyVars, "var", nil, "Set a template variable (name=value); repeatable")
+	copyCmd.Flags().BoolVar(&copyOSC52, "osc52", false, "Always copy through the terminal with OSC 52")
+}
+
+// copyResult is the machine readable result of copy
+type copyResult struct {
+	Snippet string `json:"snippet" yaml:"snippet"`
+	Bytes   int    `json:"bytes" yaml:"bytes"`
+	Method  string `json:"method" yaml:"method"` // clipboard or osc52
+}
+
+// copySnippet renders the snippet's template variables and copies the code
+func copySnippet(snippet *internal.Snippet) (*copyResult, error) {
+	code, err := renderSnippetTemplate(snippet.Code, copyVars)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(code) > largeSnippetSize {
+		statusln(ui.Warning.Render(fmt.Sprintf("Warning: %s is %s", snippet.ShortID, internal.FormatSize(int64(len(code))))))
+		if !internal.YesNoPrompt("Copy it anyway?", true) {
+			return nil, exitErrorf(ExitError, "copy cancelled")
+		}
+	}
+
+	method, err := internal.CopyToClipboard(code, copyOSC52)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy snippet: %w", err)
+	}
+	if method == internal.ClipboardOSC52 && len(code) > internal.OSC52Limit {
+		statusln(ui.Warning.Render("Warning: some terminals truncate or ignore OSC 52 copies this large"))
+	}
+	internal.Info("Copied snippet", map[string]interface{}{"snippet": snippet.ShortID, "bytes": len(code), "method": method})
+
+	via := "the clipboard"
+	if method == internal.ClipboardOSC52 {
+		via = "the terminal clipboard (OSC 52)"
+	}
+	statusln(ui.Success.Render(fmt.Sprintf("Copied %s (%s) to %s", snippet.ShortID, internal.FormatSize(int64(len(code))), via)))
+	return &copyResult{Snippet: snippet.ShortID, Bytes: len(code), Method: method}, nil
+}
+
+// renderSnippetTemplate fills in template variables from name=value pairs.
+// Variables without a value are prompted for, offering their default, when a
+// terminal is available.
+func renderSnippetTemplate(code string, pairs []string) (string, error) {
+	values, err := internal.ParseTemplateValues(pairs)
+	if err != nil {
+		return "", withExitCode(ExitUsage, err)
+	}
+	for _, v := range internal.TemplateVars(code) {
+		if _, ok := values[v.Name]; ok || !internal.CanPrompt() || internal.AssumeYes() {
+			continue
+		}
+		values[v.Name] = internal.GetUserInput(fmt.Sprintf("Value for %s", v.Name), v.Default)
+	}
+
+	rendered, err := internal.RenderTemplate(code, values)
+	if err != nil {
+		return "", withExitCode(ExitUsage, err)
+	}
+	return rendered, nil
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"snippetkit/internal"
 
 	"github.com/spf13/cobra"
 )
@@ -11,17 +12,18 @@ var createCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create a new snippet from your terminal (coming soon)",
 	Long:  "This feature is under development and will be available in a future release.",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(titleStyle.Render("\nSnippet Creation Coming Soon"))
-		fmt.Println(divider)
-		fmt.Println(infoStyle.Render("We're working on a powerful feature that will allow you to:"))
-		fmt.Println()
-		fmt.Println("  • Create new snippets directly from the CLI")
-		fmt.Println("  • Upload them to your SnippetKit account")
-		fmt.Println()
-		fmt.Println(infoStyle.Render("In the meantime, manage your snippets at:"))
-		fmt.Println("  " + urlStyle.Render("https://snippetkit.vercel.app/snippet"))
-		fmt.Println()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fmt.Fprintln(internal.Stdout, ui.Title.Render("\nSnippet Creation Coming Soon"))
+		fmt.Fprintln(internal.Stdout, ui.Divider())
+		fmt.Fprintln(internal.Stdout, ui.Info.Render("We're working on a powerful feature that will allow you to:"))
+		fmt.Fprintln(internal.Stdout)
+		fmt.Fprintln(internal.Stdout, "  • Create new snippets directly from the CLI")
+		fmt.Fprintln(internal.Stdout, "  • Upload them to your SnippetKit account")
+		fmt.Fprintln(internal.Stdout)
+		fmt.Fprintln(internal.Stdout, ui.Info.Render("In the meantime, manage your snippets at:"))
+		fmt.Fprintln(internal.Stdout, "  "+ui.URL.Render("https://snippetkit.vercel.app/snippet"))
+		fmt.Fprintln(internal.Stdout)
+		return nil
 	},
 }
 
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"snippetkit/internal"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd checks the setup for common problems
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check your setup for common problems",
+	Long: `Check the config file, project config, proxy and CA settings, API
+reachability, credentials, log directory and terminal. Every check passes,
+warns or fails, with a hint on how to fix it.
+
+Exits with code 1 when a check fails.`,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		myspinner := internal.NewSpinner()
+		myspinner.Start("Running checks...")
+		report := newDoctorReport(internal.RunChecks())
+		myspinner.Success(fmt.Sprintf("Ran %d checks", len(report.Checks)))
+
+		if output.machine() {
+			if err := writeResult(os.Stdout, schemaDoctor, report); err != nil {
+				return err
+			}
+		} else {
+			printDoctorReport(internal.Stdout, report, true)
+		}
+		if report.Failed > 0 {
+			return reportedError(ExitError, fmt.Errorf("%d check(s) failed", report.Failed))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// doctorReport is the machine readable result of doctor
+type doctorReport struct {
+	Checks []internal.Check `json:"checks" yaml:"checks"`
+	Passed int              `json:"passed" yaml:"passed"`
+	Warned int              `json:"warned" yaml:"warned"`
+	Failed int              `json:"failed" yaml:"failed"`
+}
+
+func newDoctorReport(checks []internal.Check) doctorReport {
+	report := doctorReport{Checks: checks}
+	for _, check := range checks {
+		switch check.Status {
+		case internal.CheckPass:
+			report.Passed++
+		case internal.CheckWarn:
+			report.Warned++
+		case internal.CheckFail:
+			report.Failed++
+		}
+	}
+	return report
+}
+
+func (r doctorReport) items() []interface{} {
+	values := make([]interface{}, len(r.Checks))
+	for i, check := range r.Checks {
+		values[i] = check
+	}
+	return values
+}
+
+// printDoctorReport prints the checks for people, with colors when styled
+func printDoctorReport(w io.Writer, report doctorReport, styled bool) {
+	render := func(style func(...string) string, text string) string {
+		if !styled {
+			return text
+		}
+		return style(text)
+	}
+	for _, check := range report.Checks {
+		var status string
+		switch check.Status {
+		case internal.CheckPass:
+			status = render(ui.Success.Render, "[pass]")
+		case internal.CheckWarn:
+			status = render(ui.Warning.Render, "[warn]")
+		default:
+			status = render(ui.Error.Render, "[fail]")
+		}
+		fmt.Fprintf(w, "%s %s %s\n", status, render(ui.Label.Render, fmt.Sprintf("%-15s", check.Name)), check.Message)
+		for _, detail := range check.Details {
+			fmt.Fprintln(w, strings.Repeat(" ", 7)+"- "+detail)
+		}
+		if check.Hint != "" {
+			fmt.Fprintln(w, strings.Repeat(" ", 7)+render(ui.Info.Render, "hint: "+check.Hint))
+		}
+	}
+	fmt.Fprintf(w, "\n%d passed, %d warnings, %d failed\n", report.Passed, report.Warned, report.Failed)
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"snippetkit/internal"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes returned by snippetkit
+const (
+	ExitError    = 1 // Unexpected or unclassified error
+	ExitUsage    = 2 // Invalid arguments or flags
+	ExitAuth     = 3 // Missing, invalid or expired API token
+	ExitNotFound = 4 // Snippet or profile not found
+	ExitNetwork  = 5 // API unreachable or failing
+	ExitConflict = 6 // Target file already exists or would be clobbered
+	ExitPartial  = 7 // Some items of a multi-item operation failed
+)
+
+// exitCodesHelp documents the exit codes in --help
+const exitCodesHelp = `Exit codes:
+  0  Success
+  1  Unexpected error
+  2  Invalid arguments or flags
+  3  Authentication required or failed
+  4  Snippet or profile not found
+  5  Network or API failure
+  6  Conflict with an existing file
+  7  Partial failure`
+
+// commandError is an error that carries the exit code of the command
+type commandError struct {
+	code     int
+	err      error
+	reported bool // The command already reported the failure in its output
+}
+
+func (e *commandError) Error() string {
+	return e.err.Error()
+}
+
+func (e *commandError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode attaches an exit code to err
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &commandError{code: code, err: err}
+}
+
+// reportedError is like withExitCode for failures the command has already
+// shown in its output, so they only set the exit code
+func reportedError(code int, err error) error {
+	return &commandError{code: code, err: err, reported: true}
+}
+
+// exitErrorf creates an error with the given exit code
+func exitErrorf(code int, format string, args ...interface{}) error {
+	return withExitCode(code, fmt.Errorf(format, args...))
+}
+
+// exitCode maps an error returned by a command to the process exit code
+func exitCode(err error) int {
+	var cmdErr *commandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.code
+	}
+
+	var netErr *internal.NetworkError
+	var apiErr *internal.APIError
+	switch {
+	case errors.Is(err, internal.ErrUnauthorized), errors.Is(err, internal.ErrBadPassphrase):
+		return ExitAuth
+	case errors.Is(err, internal.ErrNotFound), errors.Is(err, internal.ErrProfileNotFound):
+		return ExitNotFound
+	case errors.As(err, &netErr), errors.As(err, &apiErr), errors.Is(err, internal.ErrOffline):
+		return ExitNetwork
+	case strings.HasPrefix(err.Error(), "unknown command"):
+		return ExitUsage
+	}
+	return ExitError
+}
+
+// usageArgs wraps a cobra argument validator so its errors exit with ExitUsage
+func usageArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		return withExitCode(ExitUsage, validate(cmd, args))
+	}
+}
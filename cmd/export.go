@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"snippetkit/internal"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	htmlformatter "github.com/alecthomas/chroma/v2/formatters/html"
+	svgformatter "github.com/alecthomas/chroma/v2/formatters/svg"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/spf13/cobra"
+)
+
+// Export formats
+const (
+	exportHTML = "html"
+	exportSVG  = "svg"
+)
+
+// Flags
+var (
+	exportFormat      string
+	exportTheme       string
+	exportFile        string
+	exportLineNumbers bool
+	exportHighlight   string
+	exportNoHeader    bool
+)
+
+// exportCmd renders a snippet as a standalone HTML page or SVG image
+var exportCmd = &cobra.Command{
+	Use:   "export [snippet ID]",
+	Short: "Export a snippet as highlighted HTML or SVG",
+	Long: `Render a snippet as a standalone HTML page or SVG image for docs and slides.
+The title and description are shown above the code unless --no-header is set.
+The format defaults to the extension of --file, or html.`,
+	Example: `  snippetkit export abc123 --format svg --file snippet.svg
+  snippetkit export abc123 --theme github --line-numbers --highlight 3-5,9 > snippet.html`,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snippetID := args[0]
+
+		format := strings.ToLower(exportFormat)
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(exportFile)), ".")
+			if format != exportSVG {
+				format = exportHTML
+			}
+		}
+		if format != exportHTML && format != exportSVG {
+			return exitErrorf(ExitUsage, "unknown export format %q (expected html or svg)", exportFormat)
+		}
+		theme := exportTheme
+		if theme == "" {
+			theme = internal.Theme()
+		}
+		if _, ok := styles.Registry[theme]; !ok {
+			return exitErrorf(ExitUsage, "unknown theme %q (run 'snippetkit themes --list' to see them)", theme)
+		}
+		highlights, err := parseHighlightRanges(exportHighlight)
+		if err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+
+		apiToken, err := requireAPIKey()
+		if err != nil {
+			return err
+		}
+
+		myspinner := internal.NewSpinner()
+		myspinner.Start(fmt.Sprintf("Fetching snippet %s...", snippetID))
+		snippet, err := internal.FetchSnippet(snippetID, apiToken)
+		if err != nil {
+			myspinner.Error(fmt.Sprintf("Failed to fetch snippet with ID: %s", snippetID))
+			internal.Error("Failed to fetch snippet", err, nil)
+			return fmt.Errorf("failed to fetch snippet %s: %w", snippetID, err)
+		}
+		myspinner.Success(fmt.Sprintf("Snippet %s fetched successfully", snippetID))
+
+		var buf bytes.Buffer
+		if format == exportSVG {
+			err = exportSnippetSVG(&buf, snippet, styles.Get(theme), highlights)
+		} else {
+			err = exportSnippetHTML(&buf, snippet, styles.Get(theme), highlights)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to export snippet: %v", err)
+		}
+
+		if exportFile == "" || exportFile == "-" {
+			_, err := os.Stdout.Write(buf.Bytes())
+			return err
+		}
+		if err := os.WriteFile(exportFile, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %v", exportFile, err)
+		}
+		internal.Info("Exported snippet", map[string]interface{}{"snippet": snippetID, "file": exportFile, "format": format})
+		statusln(ui.Success.Render(fmt.Sprintf("Exported %s to %s", snippetID, exportFile)))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&exportFormat, "format", "", "Export format: html or svg")
+	exportCmd.Flags().StringVar(&exportTheme, "theme", "", "Highlighting theme (default: the theme setting)")
+	exportCmd.Flags().StringVar(&exportFile, "file", "", "Write to this file instead of stdout")
+	exportCmd.Flags().BoolVarP(&exportLineNumbers, "line-numbers", "N", false, "Show line numbers")
+	exportCmd.Flags().StringVar(&exportHighlight, "highlight", "", "Highlight lines, e.g. 3-5,9")
+	exportCmd.Flags().BoolVar(&exportNoHeader, "no-header", false, "Leave out the title and description")
+}
+
+// parseHighlightRanges parses comma separated line ranges such as 3-5,9
+func parseHighlightRanges(value string) ([][2]int, error) {
+	var ranges [][2]int
+	for _, part := range strings.Split(value, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		r, err := parseLineRange(part)
+		if err != nil {
+			return nil, err
+		}
+		if r.end == 0 {
+			r.end = int(^uint(0) >> 1) // To the last line
+		}
+		ranges = append(ranges, [2]int{r.start, r.end})
+	}
+	return ranges, nil
+}
+
+// lineHighlighted reports whether line is in one of the ranges
+func lineHighlighted(ranges [][2]int, line int) bool {
+	for _, r := range ranges {
+		if line >= r[0] && line <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// exportSnippetHTML writes a standalone HTML page
+func exportSnippetHTML(w io.Writer, snippet *internal.Snippet, style *chroma.Style, highlights [][2]int) error {
+	iterator, err := chroma.Coalesce(codeLexer(snippet.Code, snippet.Language)).Tokenise(nil, snippet.Code)
+	if err != nil {
+		return err
+	}
+
+	background := style.Get(chroma.Background)
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", html.EscapeString(snippet.Title))
+	fmt.Fprintf(w, "<style>\nbody { margin: 0; padding: 1.5em; background-color: %s; color: %s; font-family: sans-serif; }\n",
+		background.Background, background.Colour)
+	fmt.Fprint(w, "header h1 { margin: 0 0 0.25em; font-size: 1.4em; }\nheader p { margin: 0 0 1em; opacity: 0.75; }\npre { margin: 0; }\n</style>\n</head>\n<body>\n")
+	if !exportNoHeader {
+		fmt.Fprintf(w, "<header>\n<h1>%s</h1>\n", html.EscapeString(snippet.Title))
+		if snippet.Description != "" {
+			fmt.Fprintf(w, "<p>%s</p>\n", html.EscapeString(snippet.Description))
+		}
+		fmt.Fprint(w, "</header>\n")
+	}
+
+	formatter := htmlformatter.New(
+		htmlformatter.WithLineNumbers(exportLineNumbers),
+		htmlformatter.HighlightLines(highlights),
+		htmlformatter.TabWidth(4),
+	)
+	if err := formatter.Format(w, style, iterator); err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(w, "\n</body>\n</html>\n")
+	return err
+}
+
+// exportSnippetSVG writes an SVG image. The SVG formatter has no header, line
+// number or line highlighting support, so the header and line numbers are
+// added as tokens and highlighted lines get a background rectangle.
+func exportSnippetSVG(w io.Writer, snippet *internal.Snippet, style *chroma.Style, highlights [][2]int) error {
+	iterator, err := chroma.Coalesce(codeLexer(snippet.Code, snippet.Language)).Tokenise(nil, strings.TrimSuffix(snippet.Code, "\n")+"\n")
+	if err != nil {
+		return err
+	}
+	lines := chroma.SplitTokensIntoLines(iterator.Tokens())
+	gutter := len(fmt.Sprint(len(lines)))
+
+	var tokens []chroma.Token
+	headerLines := 0
+	if !exportNoHeader {
+		// The title and description are collapsed to one line each, so the
+		// header takes as many lines as are counted here
+		tokens = append(tokens, chroma.Token{Type: chroma.GenericHeading, Value: oneLine(snippet.Title) + "\n"})
+		headerLines++
+		if description := oneLine(snippet.Description); description != "" {
+			tokens = append(tokens, chroma.Token{Type: chroma.Comment, Value: description + "\n"})
+			headerLines++
+		}
+		tokens = append(tokens, chroma.Token{Type: chroma.Text, Value: "\n"})
+		headerLines++
+	}
+	for i, line := range lines {
+		if exportLineNumbers {
+			tokens = append(tokens, chroma.Token{Type: chroma.LineNumbers, Value: fmt.Sprintf("%*d  ", gutter, i+1)})
+		}
+		tokens = append(tokens, line...)
+	}
+
+	var buf bytes.Buffer
+	if err := svgformatter.New().Format(&buf, style, chroma.Literator(tokens...)); err != nil {
+		return err
+	}
+	out := buf.String()
+
+	// Draw the highlighted lines behind the text, matching the formatter's
+	// 1.2em line height
+	if len(highlights) > 0 {
+		fill := style.Get(chroma.LineHighlight).Background
+		if !fill.IsSet() {
+			fill = style.Get(chroma.Background).Background.BrightenOrDarken(0.1)
+		}
+		var rects strings.Builder
+		for i := range lines {
+			if lineHighlighted(highlights, i+1) {
+				y := 1.2*float64(headerLines+i) + 0.25
+				fmt.Fprintf(&rects, "<rect x=\"0\" y=\"%fem\" width=\"100%%\" height=\"1.2em\" fill=\"%s\"/>\n", y, fill)
+			}
+		}
+		if at := strings.Index(out, "<g "); at >= 0 {
+			out = out[:at] + rects.String() + out[at:]
+		}
+	}
+	_, err = io.WriteString(w, out)
+	return err
+}
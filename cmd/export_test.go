@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"snippetkit/internal"
+
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+func TestExportSVGHighlightsBelowMultiLineDescription(t *testing.T) {
+	snippet := &internal.Snippet{
+		Title:       "Retry",
+		Description: "Retries requests.\nUses exponential backoff.\n\nSafe for GET only.",
+		Language:    "go",
+		Code:        "package retry\n\nfunc Do() {}\n",
+	}
+	var buf bytes.Buffer
+	if err := exportSnippetSVG(&buf, snippet, styles.Get("monokai"), [][2]int{{1, 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Title, description and a blank line come before the first code line
+	rect := regexp.MustCompile(`<rect x="0" y="([0-9.]+)em"`).FindStringSubmatch(buf.String())
+	if rect == nil {
+		t.Fatalf("no highlight rectangle in %s", buf.String())
+	}
+	if y, _ := strconv.ParseFloat(rect[1], 64); y != 1.2*3+0.25 {
+		t.Errorf("highlight of line 1 at %vem, want %vem", y, 1.2*3+0.25)
+	}
+	// The SVG formatter writes spaces as &#160;
+	if !bytes.Contains(buf.Bytes(), []byte("requests.&#160;Uses&#160;exponential&#160;backoff.&#160;Safe")) {
+		t.Errorf("description wasn't collapsed to one line: %s", buf.String())
+	}
+}
@@ -1,45 +1,57 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"snippetkit/internal"
 
 	"os"
+	"strconv"
 	"strings"
 
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
 	"github.com/alecthomas/chroma/v2/lexers"
 	"github.com/alecthomas/chroma/v2/quick"
-	"github.com/leaanthony/spinner"
+	"github.com/alecthomas/chroma/v2/styles"
 
 	"github.com/spf13/cobra"
 )
 
 var jsonOutput bool
 var fullOutput bool
+var lineNumbers bool
+var lineRangeFlag string
+var noPager bool
+var infoCopy bool
 
 // infoCmd represents the info command
 var infoCmd = &cobra.Command{
 	Use:   "info [snippet ID]",
 	Short: "Preview a snippet before adding it",
-	Long:  `The 'info' command retrieves metadata and a preview of the snippet code from SnippetKit's API.`,
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `The 'info' command retrieves metadata and a preview of the snippet code from SnippetKit's API.
+
+The preview shows the first preview_lines lines (10 by default). Output taller
+than the terminal is shown through $PAGER, or 'less -R' when it isn't set.`,
+	Example: `  snippetkit info abc123 --full --line-numbers
+  snippetkit info abc123 --lines 40-80`,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		snippetID := args[0]
 
-		checkSpinner := spinner.New()
-		checkSpinner.Start("Checking auth status...")
-		apiToken, err := internal.GetAPIKey()
-		if err != nil {
-			checkSpinner.Error("Failed to authenticate")
-			fmt.Print("Error authenticating. Run 'snippetkit login' to authenticate.")
-			internal.Error("Failed to get API key", err, nil)
-			return
+		var lines lineRange
+		if lineRangeFlag != "" {
+			var err error
+			if lines, err = parseLineRange(lineRangeFlag); err != nil {
+				return withExitCode(ExitUsage, err)
+			}
 		}
 
-		checkSpinner.Success("Authenticated successfully")
+		apiToken, err := requireAPIKey()
+		if err != nil {
+			return err
+		}
 
-		myspinner := spinner.New()
+		myspinner := internal.NewSpinner()
 		myspinner.Start(fmt.Sprintf("Fetching snippet %s...", snippetID))
 
 		// Fetch snippet from API
@@ -47,63 +59,201 @@ var infoCmd = &cobra.Command{
 		if err != nil {
 			myspinner.Error(fmt.Sprintf("Failed to fetch snippet with ID: %s", snippetID))
 			internal.Error("Failed to fetch snippet", err, nil)
-			return
+			return fmt.Errorf("failed to fetch snippet %s: %w", snippetID, err)
 		}
 		myspinner.Success(fmt.Sprintf("Snippet %s fetched successfully", snippetID))
 
-		// Output JSON if requested
-		if jsonOutput {
-			jsonData, _ := json.MarshalIndent(snippet, "", "  ")
-			fmt.Println(string(jsonData))
-			return
+		// Copy before printing so the pager doesn't hide prompts and warnings
+		if infoCopy {
+			if _, err := copySnippet(snippet); err != nil {
+				return err
+			}
 		}
 
-		// Print snippet metadata
-		fmt.Println(titleStyle.Render("> Snippet Details:"))
-		fmt.Println(divider)
-		fmt.Println(labelStyle.Render("ID: ") + snippetID)
-		fmt.Println(labelStyle.Render("Title: ") + snippet.Title)
-		fmt.Println(labelStyle.Render("Language: ") + snippet.Language)
-		fmt.Println(labelStyle.Render("Tags: ") + strings.Join(snippet.Tags, ", "))
-		fmt.Println(labelStyle.Render("Path: ") + snippet.Path)
-		fmt.Println(divider)
-		fmt.Println(titleStyle.Render("Code Preview"))
-		fmt.Println(divider)
-
-		// Trim or show full code
-		code := snippet.Code
-		if !fullOutput {
-			code = formatCodePreview(code)
+		// Output machine readable formats if requested
+		if output.machine() {
+			return writeResult(os.Stdout, schemaSnippet, newSnippetView(snippet, true))
+		}
+
+		// Select the lines to show
+		total := len(codeLines(snippet.Code))
+		heading := "Code"
+		switch {
+		case lineRangeFlag != "":
+			if lines.start > total {
+				return exitErrorf(ExitUsage, "line %d is past the end of the snippet (%d lines)", lines.start, total)
+			}
+			if lines.end == 0 || lines.end > total {
+				lines.end = total
+			}
+			heading = fmt.Sprintf("Lines %d-%d of %d", lines.start, lines.end, total)
+		case fullOutput:
+			lines = lineRange{start: 1, end: total}
+		default:
+			lines = lineRange{start: 1, end: min(internal.PreviewLines(), total)}
+			heading = "Code Preview"
 		}
 
-		// Print syntax-highlighted code
-		printHighlightedCode(code, snippet.Language)
+		// Print snippet metadata
+		var b strings.Builder
+		fmt.Fprintln(&b, ui.Title.Render("> Snippet Details:"))
+		fmt.Fprintln(&b, ui.Divider())
+		fmt.Fprintln(&b, ui.Label.Render("ID: ")+snippetID)
+		fmt.Fprintln(&b, ui.Label.Render("Title: ")+snippet.Title)
+		fmt.Fprintln(&b, ui.Label.Render("Language: ")+snippet.Language)
+		fmt.Fprintln(&b, ui.Label.Render("Tags: ")+strings.Join(snippet.Tags, ", "))
+		fmt.Fprintln(&b, ui.Label.Render("Path: ")+snippet.Path)
+		fmt.Fprintln(&b, ui.Divider())
+		fmt.Fprintln(&b, ui.Title.Render(heading))
+		fmt.Fprintln(&b, ui.Divider())
+
+		// Syntax-highlighted code
+		b.WriteString(renderCodeLines(snippet.Code, snippet.Language, lines, lineNumbers))
+		if lineRangeFlag == "" && !fullOutput && lines.end < total {
+			fmt.Fprintln(&b, ui.Info.Render(fmt.Sprintf("... (preview truncated, %d more lines; use --full or --lines)", total-lines.end)))
+		}
+		fmt.Fprintln(&b, ui.Divider())
 
-		fmt.Println(divider)
+		if noPager {
+			fmt.Fprint(internal.Stdout, b.String())
+			return nil
+		}
+		return internal.PageOutput(b.String())
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(infoCmd)
-	infoCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output snippet info as JSON")
+	infoCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output snippet info as JSON (same as --output json)")
 	infoCmd.Flags().BoolVarP(&fullOutput, "full", "f", false, "Show full snippet instead of a preview")
+	infoCmd.Flags().BoolVarP(&lineNumbers, "line-numbers", "N", false, "Show line numbers")
+	infoCmd.Flags().StringVar(&lineRangeFlag, "lines", "", "Show a range of lines, e.g. 40-80, 40- or -80")
+	infoCmd.Flags().BoolVar(&noPager, "no-pager", false, "Never page the output")
+	infoCmd.Flags().BoolVar(&infoCopy, "copy", false, "Also copy the code to the clipboard (see 'snippetkit copy')")
+	infoCmd.Flags().StringArrayVar(&copyVars, "var", nil, "Set a template variable for --copy (name=value); repeatable")
+	infoCmd.Flags().BoolVar(&copyOSC52, "osc52", false, "Copy through the terminal with OSC 52")
+}
+
+// lineRange is a range of lines counted from 1. An end of zero means the last
+// line.
+type lineRange struct {
+	start int
+	end   int
+}
+
+// parseLineRange parses a range such as 40-80, 40-, -80 or 40
+func parseLineRange(value string) (lineRange, error) {
+	invalid := fmt.Errorf("invalid line range %q (expected e.g. 40-80, 40- or -80)", value)
+	from, to, isRange := strings.Cut(strings.TrimSpace(value), "-")
+	r := lineRange{start: 1}
+	if from != "" {
+		n, err := strconv.Atoi(from)
+		if err != nil || n < 1 {
+			return r, invalid
+		}
+		r.start = n
+	}
+	switch {
+	case !isRange:
+		r.end = r.start
+	case to != "":
+		n, err := strconv.Atoi(to)
+		if err != nil || n < r.start {
+			return r, invalid
+		}
+		r.end = n
+	}
+	if from == "" && to == "" {
+		return r, invalid
+	}
+	return r, nil
+}
+
+// codeLines splits code into lines, ignoring a trailing newline
+func codeLines(code string) []string {
+	return strings.Split(strings.TrimSuffix(code, "\n"), "\n")
+}
+
+// renderCodeLines highlights code and returns the lines in r
+func renderCodeLines(code, language string, r lineRange, numbers bool) string {
+	highlighted := highlightCodeLines(code, language)
+	end := min(r.end, len(highlighted))
+	width := len(strconv.Itoa(end))
+
+	var b strings.Builder
+	for i := r.start - 1; i < end; i++ {
+		if numbers {
+			b.WriteString(ui.Info.Render(fmt.Sprintf("%*d ", width, i+1)) + "│ ")
+		}
+		b.WriteString(highlighted[i] + "\n")
+	}
+	return b.String()
 }
 
-// formatCodePreview trims and formats the snippet code for preview
-func formatCodePreview(code string) string {
-	lines := strings.Split(code, "\n")
-	maxLines := 10 // Limit preview to 10 lines
+// highlightCode returns the code highlighted with the configured theme
+func highlightCode(code, language string) string {
+	return highlightCodeWith(code, language, internal.Theme())
+}
 
-	if len(lines) > maxLines {
-		return strings.Join(lines[:maxLines], "\n") + "\n... (preview truncated)"
+// highlightCodeWith returns the code highlighted with a chroma style, in the
+// colors the terminal supports
+func highlightCodeWith(code, language, theme string) string {
+	formatter := internal.ChromaFormatter()
+	if formatter == "noop" {
+		return code
 	}
 
-	return strings.Join(lines, "\n")
+	// Highlight the code
+	var buf strings.Builder
+	err := quick.Highlight(&buf, code, codeLexer(code, language).Config().Name, formatter, theme)
+	if err != nil {
+		internal.Warn("Error highlighting code, falling back to plain text", nil)
+		return code
+	}
+	return buf.String()
 }
 
-// printHighlightedCode prints the snippet with syntax highlighting
-func printHighlightedCode(code, language string) {
-	// Determine lexer (detect from language or analyze content)
+// highlightCodeLines highlights code with the configured theme and splits it
+// into lines. The whole snippet is lexed so multi-line constructs such as
+// block comments are recognized, and their tokens are split at the line ends
+// so every line starts and ends with its own colors.
+func highlightCodeLines(code, language string) []string {
+	lines := codeLines(code)
+	name := internal.ChromaFormatter()
+	if name == "noop" {
+		return lines
+	}
+
+	iterator, err := chroma.Coalesce(codeLexer(code, language)).Tokenise(nil, strings.Join(lines, "\n")+"\n")
+	if err != nil {
+		internal.Warn("Error highlighting code, falling back to plain text", nil)
+		return lines
+	}
+	formatter := formatters.Get(name)
+	style := styles.Get(internal.Theme())
+
+	highlighted := make([]string, 0, len(lines))
+	for _, tokens := range chroma.SplitTokensIntoLines(iterator.Tokens()) {
+		// The newline is left out so it isn't wrapped in colors
+		last := &tokens[len(tokens)-1]
+		last.Value = strings.TrimSuffix(last.Value, "\n")
+		var buf strings.Builder
+		if err := formatter.Format(&buf, style, chroma.Literator(tokens...)); err != nil {
+			internal.Warn("Error highlighting code, falling back to plain text", nil)
+			return lines
+		}
+		highlighted = append(highlighted, buf.String())
+	}
+	// The lexer may drop a trailing empty line
+	for len(highlighted) < len(lines) {
+		highlighted = append(highlighted, "")
+	}
+	return highlighted
+}
+
+// codeLexer returns the lexer for language, detecting it from the code when
+// the language is unknown
+func codeLexer(code, language string) chroma.Lexer {
 	lexer := lexers.Get(language)
 	if lexer == nil {
 		lexer = lexers.Analyse(code) // Auto-detect language
@@ -111,10 +261,5 @@ func printHighlightedCode(code, language string) {
 	if lexer == nil {
 		lexer = lexers.Fallback // Default to plaintext if no match
 	}
-
-	// Highlight and print the code
-	err := quick.Highlight(os.Stdout, code, lexer.Config().Name, "terminal16m", "onedark")
-	if err != nil {
-		internal.Warn("Error highlighting code, falling back to plain text", nil)
-	}
+	return lexer
 }
@@ -1,27 +1,75 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 	"snippetkit/internal"
 
-	"github.com/leaanthony/spinner"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 )
 
-var apiKey string
+var (
+	apiKey        string
+	loginHelper   string
+	loginWeb      bool
+	loginCallback bool
+	loginNoOpen   bool
+)
 
 // loginCmd represents the login command
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Authenticate and store your API token",
-	Long:  "Use this command to set and save your API token in config.yaml for authentication.",
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `Use this command to set and save your API token for authentication. The token
+is stored for the active profile; pick another with --profile.
+
+Where the token is kept is chosen with --helper, which is remembered for the
+profile, or the credential_helper setting:
+
+  plaintext  config.yaml, only readable by you (the default)
+  file       credentials.enc, encrypted with a passphrase that is asked for
+             or read from SNIPPETKIT_PASSPHRASE
+  <name>     the external program snippetkit-credential-<name> on the PATH,
+             called like a git credential helper with get, store or erase and
+             key=value lines (protocol, host, profile, token) on stdin. Paths
+             aren't accepted, so a config file can't run other programs.
+
+With --web you log in through the browser instead of pasting a token: a code
+is shown to confirm on the SnippetKit website while the CLI waits. Add
+--callback to have the website redirect back to the CLI on localhost instead,
+which skips typing the code but needs a browser on the same machine.`,
+	Example: `  snippetkit login
+  snippetkit login --web
+  snippetkit login --key "$TOKEN" --helper file --profile work`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		var apiToken string
 		var err error
 
-		if apiKey != "" {
+		if loginHelper != "" {
+			if _, err := internal.NewCredentialStore(loginHelper); err != nil {
+				return withExitCode(ExitUsage, err)
+			}
+		}
+
+		loginWeb = loginWeb || loginCallback
+		if loginWeb && apiKey != "" {
+			return exitErrorf(ExitUsage, "--web and --key can't be used together")
+		}
+
+		if loginWeb {
+			apiToken, err = webLogin()
+			if err != nil {
+				return err
+			}
+		} else if apiKey != "" {
 			apiToken = apiKey
+		} else if !internal.CanPrompt() {
+			internal.Error("Cannot prompt for API token", internal.ErrNoInput, nil)
+			return exitErrorf(ExitUsage, "no API token provided. Pass it with --key when running non-interactively")
 		} else {
 			// Prompt user for API token
 			prompt := promptui.Prompt{
@@ -34,46 +82,116 @@ var loginCmd = &cobra.Command{
 					return nil
 				},
 				AllowEdit: true, // Allows pasting without character-by-character input
+				Stdout:    os.Stderr,
 			}
 
 			apiToken, err = prompt.Run()
 			if err == promptui.ErrInterrupt {
-				fmt.Println(errorStyle.Render("\n Login cancelled by user."))
 				internal.Info("Login cancelled.", nil)
-				return
+				return fmt.Errorf("login cancelled by user")
 			}
 			if err != nil {
-				fmt.Println(errorStyle.Render("\n Error reading input"))
 				internal.Error("Error reading API token input", err, nil)
-				return
+				return fmt.Errorf("error reading input: %v", err)
 			}
 		}
 
-		myspinner := spinner.New()
-		myspinner.Start("Saving API token...")
-		// Save the token using internal function
-		success, apiErr := internal.SetAPIKey(apiToken)
+		myspinner := internal.NewSpinner()
+		myspinner.Start("Verifying API token...")
+		if err := internal.CheckAPIKey(apiToken); err != nil {
+			myspinner.Error("Failed to verify API token")
+			return err
+		}
+		myspinner.Success("API token verified")
 
-		if apiErr != nil {
-			myspinner.Error("API token invalid or expired")
+		// The store may ask for a passphrase, so it runs without a spinner
+		store, err := internal.SetAPIKey(apiToken, loginHelper)
+		if err != nil {
 			internal.Error("Error saving API token", err, nil)
-			return
+			return err
 		}
+		internal.Info("A new API token was saved successfully!", map[string]interface{}{"profile": internal.ActiveProfileName(), "store": store})
+		statusln(ui.Success.Render("API token saved successfully!"))
 
-		// Confirmation message
-		if success {
-			myspinner.Success("API token saved successfully!")
-			internal.Info("A new API token was saved successfully!", nil)
-
-			// Show where the API key is stored
-			configPath := internal.GetConfigPath()
-			fmt.Println(infoStyle.Render(fmt.Sprintf("\n API key stored in: %s", configPath)))
+		// Show where the API key is stored
+		switch store {
+		case internal.CredentialPlaintext:
+			statusln(ui.Info.Render(fmt.Sprintf("\n API key stored in: %s (profile %s)", internal.ConfigFile(), internal.ActiveProfileName())))
+			statusln(ui.Warning.Render(" Warning: the token is stored unencrypted. Use 'snippetkit login --helper file' to encrypt it,\n or --helper <name> for a snippetkit-credential-<name> helper."))
+		case internal.CredentialFile:
+			statusln(ui.Info.Render(fmt.Sprintf("\n API key stored encrypted in: %s (profile %s)", internal.CredentialsFile(), internal.ActiveProfileName())))
+		default:
+			statusln(ui.Info.Render(fmt.Sprintf("\n API key stored with credential helper %s (profile %s)", store, internal.ActiveProfileName())))
 		}
-
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(loginCmd)
 	loginCmd.Flags().StringVarP(&apiKey, "key", "k", "", "API token to authenticate")
+	loginCmd.Flags().BoolVar(&loginWeb, "web", false, "Log in through the browser with a one-time code")
+	loginCmd.Flags().BoolVar(&loginCallback, "callback", false, "With --web, receive the token through a redirect to localhost")
+	loginCmd.Flags().BoolVar(&loginNoOpen, "no-browser", false, "With --web, print the login URL without opening a browser")
+	loginCmd.Flags().StringVar(&loginHelper, "helper", "", "Store the token with plaintext, file (encrypted) or a snippetkit-credential-<name> helper")
+}
+
+// webLogin gets a token through the browser, with the device code flow or a
+// localhost callback
+func webLogin() (string, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	open := func(loginURL string) {
+		// The URL is needed to log in, so it's shown even with --quiet
+		fmt.Fprintln(internal.Stderr, ui.Info.Render("Open this URL in your browser to log in:"))
+		fmt.Fprintln(internal.Stderr, "  "+ui.URL.Render(loginURL))
+		if loginNoOpen || !internal.CanPrompt() {
+			return
+		}
+		if err := internal.OpenBrowser(loginURL); err != nil {
+			internal.Warn("Failed to open browser", map[string]interface{}{"error": err.Error()})
+		}
+	}
+
+	var token string
+	var err error
+	myspinner := internal.NewSpinner()
+	if loginCallback {
+		open := func(loginURL string) {
+			open(loginURL)
+			myspinner.Start("Waiting for the browser login...")
+		}
+		token, err = internal.CallbackLogin(ctx, open)
+	} else {
+		var code *internal.DeviceCode
+		code, err = internal.RequestDeviceCode(ctx)
+		if err != nil {
+			internal.Error("Failed to start browser login", err, nil)
+			return "", fmt.Errorf("failed to start browser login: %w", err)
+		}
+		loginURL := code.VerificationURIComplete
+		if loginURL == "" {
+			loginURL = code.VerificationURI
+		}
+		open(loginURL)
+		fmt.Fprintln(internal.Stderr, ui.Label.Render("Confirm this code: ")+ui.Title.Render(code.UserCode))
+		myspinner.Start("Waiting for the login to be approved...")
+		token, err = internal.PollDeviceToken(ctx, code)
+	}
+
+	switch {
+	case err == nil:
+		myspinner.Success("Browser login approved")
+		return token, nil
+	case errors.Is(err, context.Canceled):
+		myspinner.Error("Login cancelled")
+		return "", fmt.Errorf("login cancelled by user")
+	case errors.Is(err, internal.ErrAuthDenied), errors.Is(err, internal.ErrAuthExpired):
+		myspinner.Error("Browser login failed")
+		return "", withExitCode(ExitAuth, err)
+	}
+	myspinner.Error("Browser login failed")
+	internal.Error("Browser login failed", err, nil)
+	return "", err
 }
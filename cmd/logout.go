@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"snippetkit/internal"
 
 	"github.com/manifoldco/promptui"
@@ -12,41 +13,48 @@ import (
 var logoutCmd = &cobra.Command{
 	Use:   "logout",
 	Short: "Log out and remove your API token",
-	Long:  "Use this command to log out and remove your API token from the configuration file.",
-	Run: func(cmd *cobra.Command, args []string) {
-		// Prompt user for confirmation
-		prompt := promptui.Prompt{
-			Label:     "Are you sure you want to log out? (y/N)",
-			IsConfirm: true,
-		}
-
-		result, err := prompt.Run()
-		if err == promptui.ErrInterrupt {
-			fmt.Println(errorStyle.Render("\n Logout cancelled by user."))
-			internal.Info("Logout cancelled.", nil)
-			return
-		}
-
-		if result != "y" && result != "Y" {
-			fmt.Println(errorStyle.Render("\n Error reading logout confirmation input (y/N)"))
-			internal.Error("Error reading logout confirmation input", err, nil)
-			return
-		}
-		if err != nil {
-			fmt.Println(errorStyle.Render("\n Error reading input"))
-			internal.Error("Error reading logout confirmation input", err, nil)
-			return
+	Long:  "Use this command to log out and remove your API token from the configuration file.\nOnly the active profile is logged out; pick another with --profile.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !internal.AssumeYes() {
+			// Logging out needs explicit confirmation when we can't ask for it
+			if !internal.CanPrompt() {
+				internal.Error("Cannot prompt for logout confirmation", internal.ErrNoInput, nil)
+				return exitErrorf(ExitUsage, "refusing to log out without confirmation. Re-run with --yes")
+			}
+
+			// Prompt user for confirmation
+			prompt := promptui.Prompt{
+				Label:     "Are you sure you want to log out? (y/N)",
+				IsConfirm: true,
+				Stdout:    os.Stderr,
+			}
+
+			result, err := prompt.Run()
+			if err == promptui.ErrInterrupt {
+				internal.Info("Logout cancelled.", nil)
+				return fmt.Errorf("logout cancelled by user")
+			}
+
+			if result != "y" && result != "Y" {
+				internal.Info("Logout not confirmed.", nil)
+				return fmt.Errorf("logout cancelled")
+			}
+			if err != nil {
+				internal.Error("Error reading logout confirmation input", err, nil)
+				return fmt.Errorf("error reading input: %v", err)
+			}
 		}
 
 		// Remove the API token
 		if err := internal.RemoveAPIKey(); err != nil {
 			internal.Error("Error removing API token", err, nil)
-			fmt.Println(errorStyle.Render("\n Error removing API token"))
-			return
+			return fmt.Errorf("error removing API token: %v", err)
 		}
 
-		internal.Info("Successfully logged out and removed API token", nil)
-		fmt.Println(successStyle.Render("\n Successfully logged out and removed API token"))
+		profile := internal.ActiveProfileName()
+		internal.Info("Successfully logged out and removed API token", map[string]interface{}{"profile": profile})
+		statusln(ui.Success.Render(fmt.Sprintf("\n Successfully logged out of profile %s and removed API token", profile)))
+		return nil
 	},
 }
 
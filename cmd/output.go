@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"snippetkit/internal"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by --output
+const (
+	formatTable    = "table"
+	formatJSON     = "json"
+	formatYAML     = "yaml"
+	formatTemplate = "template"
+)
+
+// Schemas of the machine readable documents. Bump the version when a field is
+// removed or changes meaning; adding fields is backwards compatible.
+const (
+	schemaSearch   = "snippetkit.search/v1"
+	schemaSnippet  = "snippetkit.snippet/v1"
+	schemaInstall  = "snippetkit.install/v1"
+	schemaError    = "snippetkit.error/v1"
+	schemaCache    = "snippetkit.cache/v1"
+	schemaThemes   = "snippetkit.themes/v1"
+	schemaCopy     = "snippetkit.copy/v1"
+	schemaProfiles = "snippetkit.profiles/v1"
+	schemaConfig   = "snippetkit.config/v1"
+	schemaAuth     = "snippetkit.auth/v1"
+	schemaDoctor   = "snippetkit.doctor/v1"
+	schemaBundle   = "snippetkit.bundle/v1"
+)
+
+// outputFormat describes how command results are written
+type outputFormat struct {
+	Name     string
+	Template *template.Template
+}
+
+// output is the format selected with --output
+var output = outputFormat{Name: formatTable}
+
+// parseOutputFormat parses the value of --output
+func parseOutputFormat(value string) (outputFormat, error) {
+	name, text, hasText := strings.Cut(value, "=")
+	switch name {
+	case "", formatTable:
+		return outputFormat{Name: formatTable}, nil
+	case formatJSON, formatYAML:
+		return outputFormat{Name: name}, nil
+	case formatTemplate:
+		if !hasText || text == "" {
+			return outputFormat{}, fmt.Errorf("template output needs a template, e.g. --output template='{{.ShortID}} {{.Title}}'")
+		}
+		tmpl, err := template.New("output").Parse(text)
+		if err != nil {
+			return outputFormat{}, fmt.Errorf("invalid output template: %v", err)
+		}
+		return outputFormat{Name: formatTemplate, Template: tmpl}, nil
+	}
+	return outputFormat{}, fmt.Errorf("unknown output format %q (expected json, yaml, table or template=...)", value)
+}
+
+// machine reports whether results are written for programs instead of people
+func (o outputFormat) machine() bool {
+	return o.Name != formatTable
+}
+
+// document is the envelope of every machine readable result
+type document struct {
+	Schema string      `json:"schema" yaml:"schema"`
+	Data   interface{} `json:"data" yaml:"data"`
+}
+
+// templateItems is implemented by results that are rendered once per item by
+// template output
+type templateItems interface {
+	items() []interface{}
+}
+
+// writeResult writes data in the selected machine format
+func writeResult(w io.Writer, schema string, data interface{}) error {
+	switch output.Name {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(document{Schema: schema, Data: data})
+	case formatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(document{Schema: schema, Data: data})
+	case formatTemplate:
+		values := []interface{}{data}
+		if list, ok := data.(templateItems); ok {
+			values = list.items()
+		}
+		for _, value := range values {
+			if err := output.Template.Execute(w, value); err != nil {
+				return fmt.Errorf("failed to render output template: %v", err)
+			}
+			fmt.Fprintln(w)
+		}
+		return nil
+	}
+	return fmt.Errorf("output format %q is not machine readable", output.Name)
+}
+
+// snippetView is the stable representation of a snippet in machine output
+type snippetView struct {
+	ID          string     `json:"id" yaml:"id"`
+	ShortID     string     `json:"short_id" yaml:"short_id"`
+	Title       string     `json:"title" yaml:"title"`
+	Description string     `json:"description" yaml:"description"`
+	Language    string     `json:"language" yaml:"language"`
+	Path        string     `json:"path" yaml:"path"`
+	Tags        []string   `json:"tags" yaml:"tags"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty" yaml:"updated_at,omitempty"`
+	Code        string     `json:"code,omitempty" yaml:"code,omitempty"`
+}
+
+func newSnippetView(snippet *internal.Snippet, withCode bool) snippetView {
+	view := snippetView{
+		ID:          snippet.ID,
+		ShortID:     snippet.ShortID,
+		Title:       snippet.Title,
+		Description: snippet.Description,
+		Language:    snippet.Language,
+		Path:        snippet.Path,
+		Tags:        snippet.Tags,
+	}
+	if view.Tags == nil {
+		view.Tags = []string{}
+	}
+	if !snippet.UpdatedAt.IsZero() {
+		view.UpdatedAt = &snippet.UpdatedAt
+	}
+	if withCode {
+		view.Code = snippet.Code
+	}
+	return view
+}
+
+// searchResult is the machine readable result of search
+type searchResult struct {
+	Query   string        `json:"query" yaml:"query"`
+	Lang    string        `json:"lang,omitempty" yaml:"lang,omitempty"`
+	Tag     string        `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Sort    string        `json:"sort" yaml:"sort"`
+	Count   int           `json:"count" yaml:"count"`
+	Page    *pageInfo     `json:"page,omitempty" yaml:"page,omitempty"`
+	Results []snippetView `json:"results" yaml:"results"`
+}
+
+// pageInfo describes where a page of search results sits in the full result
+// set. Total is omitted when it isn't known.
+type pageInfo struct {
+	Page       int    `json:"page" yaml:"page"`
+	Limit      int    `json:"limit" yaml:"limit"`
+	Offset     int    `json:"offset" yaml:"offset"`
+	Total      int    `json:"total,omitempty" yaml:"total,omitempty"`
+	HasMore    bool   `json:"has_more" yaml:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty" yaml:"next_cursor,omitempty"`
+	NextPage   int    `json:"next_page,omitempty" yaml:"next_page,omitempty"`
+}
+
+func newPageInfo(page int, result *internal.SearchPage) *pageInfo {
+	info := &pageInfo{
+		Page:       page,
+		Limit:      result.Limit,
+		Offset:     result.Offset,
+		Total:      result.Total,
+		HasMore:    result.HasMore,
+		NextCursor: result.NextCursor,
+	}
+	if result.HasMore {
+		info.NextPage = page + 1
+	}
+	return info
+}
+
+func (r searchResult) items() []interface{} {
+	values := make([]interface{}, len(r.Results))
+	for i, result := range r.Results {
+		values[i] = result
+	}
+	return values
+}
+
+// installPlan is the machine readable result of add
+type installPlan struct {
+	Snippet snippetView `json:"snippet" yaml:"snippet"`
+	Path    string      `json:"path" yaml:"path"`
+	Action  string      `json:"action" yaml:"action"` // create, overwrite or merge
+	Package string      `json:"package,omitempty" yaml:"package,omitempty"`
+	DryRun  bool        `json:"dry_run" yaml:"dry_run"`
+}
+
+// errorResult is the machine readable form of a failed command
+type errorResult struct {
+	Code    int    `json:"code" yaml:"code"`
+	Kind    string `json:"kind" yaml:"kind"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// exitKinds names the exit codes in error documents
+var exitKinds = map[int]string{
+	ExitError:    "error",
+	ExitUsage:    "usage",
+	ExitAuth:     "auth",
+	ExitNotFound: "not_found",
+	ExitNetwork:  "network",
+	ExitConflict: "conflict",
+	ExitPartial:  "partial",
+}
+
+// printError prints a command error once to stderr, as a document for machine
+// output and as a styled message otherwise. stdout only ever holds results.
+func printError(err error) {
+	var cmdErr *commandError
+	if errors.As(err, &cmdErr) && cmdErr.reported {
+		return
+	}
+	code := exitCode(err)
+	if output.machine() && output.Name != formatTemplate {
+		result := errorResult{Code: code, Kind: exitKinds[code], Message: err.Error()}
+		if writeErr := writeResult(os.Stderr, schemaError, result); writeErr == nil {
+			return
+		}
+	}
+	fmt.Fprintln(internal.Stderr, ui.Error.Render("Error: "+err.Error()))
+}
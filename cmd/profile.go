@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"snippetkit/internal"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Flags
+var (
+	profileBaseURL  string
+	profileDefaults []string
+)
+
+// profileCmd groups the profile commands
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage auth profiles for several SnippetKit accounts",
+	Long: `A profile is a named account with its own API token, API base URL and flag
+defaults. 'login' and 'logout' act on the active profile, which is chosen by
+--profile, then SNIPPETKIT_PROFILE, then 'profile use', then default.
+
+  current_profile: work
+  profiles:
+    work:
+      base_url: https://snippets.example.com
+      defaults:
+        lang: go`,
+}
+
+// profileListCmd lists the profiles
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the profiles",
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, err := internal.Profiles()
+		if err != nil {
+			return err
+		}
+
+		active := internal.ActiveProfileName()
+		result := profileListing{Active: active, Profiles: make([]profileView, 0, len(profiles))}
+		for _, profile := range profiles {
+			result.Profiles = append(result.Profiles, newProfileView(profile, active))
+		}
+		if output.machine() {
+			return writeResult(os.Stdout, schemaProfiles, result)
+		}
+
+		fmt.Fprintln(internal.Stdout, ui.Title.Render("> Profiles:"))
+		for _, profile := range result.Profiles {
+			name := profile.Name
+			if profile.Active {
+				name += " (active)"
+			}
+			auth := ui.Warning.Render("logged out")
+			switch {
+			case profile.LoggedIn:
+				auth = ui.Success.Render("logged in")
+			case profile.Credentials != internal.CredentialPlaintext:
+				auth = ui.Info.Render("token in " + profile.Credentials)
+			}
+			fmt.Fprintf(internal.Stdout, "* %s  %s  %s\n", ui.Label.Render(name), ui.URL.Render(profile.BaseURL), auth)
+		}
+		return nil
+	},
+}
+
+// profileUseCmd makes a profile the current one
+var profileUseCmd = &cobra.Command{
+	Use:   "use [name]",
+	Short: "Switch to a profile",
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.ToLower(args[0])
+		if err := internal.UseProfile(name); err != nil {
+			return err
+		}
+		internal.Info("Switched profile", map[string]interface{}{"profile": name})
+		statusln(ui.Success.Render(fmt.Sprintf("Switched to profile %s", name)))
+		if os.Getenv("SNIPPETKIT_PROFILE") != "" {
+			statusln(ui.Warning.Render("Note: SNIPPETKIT_PROFILE is set and takes precedence"))
+		}
+		return nil
+	},
+}
+
+// profileAddCmd adds a profile
+var profileAddCmd = &cobra.Command{
+	Use:   "add [name]",
+	Short: "Add a profile",
+	Long: `Add a profile. Run 'snippetkit login --profile <name>' afterwards to store its
+API token. Defaults set flags of any command run with the profile, unless the
+flag is given on the command line.`,
+	Example: `  snippetkit profile add work --base-url https://snippets.example.com --default lang=go
+  snippetkit login --profile work`,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.ToLower(args[0])
+		if err := internal.ValidateProfileName(name); err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		if internal.ProfileExists(name) {
+			return exitErrorf(ExitConflict, "profile %s already exists", name)
+		}
+		if profileBaseURL != "" {
+			if err := internal.ValidateBaseURL(profileBaseURL); err != nil {
+				return withExitCode(ExitUsage, err)
+			}
+		}
+		defaults, err := parseProfileDefaults(profileDefaults)
+		if err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+
+		if err := internal.AddProfile(name, profileBaseURL, defaults); err != nil {
+			return err
+		}
+		internal.Info("Added profile", map[string]interface{}{"profile": name})
+		statusln(ui.Success.Render(fmt.Sprintf("Added profile %s", name)))
+		statusln(ui.Info.Render(fmt.Sprintf("Run 'snippetkit login --profile %s' to log in", name)))
+		return nil
+	},
+}
+
+// profileRemoveCmd removes a profile and its token
+var profileRemoveCmd = &cobra.Command{
+	Use:   "remove [name]",
+	Short: "Remove a profile and its API token",
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.ToLower(args[0])
+		if !internal.ProfileExists(name) {
+			return withExitCode(ExitNotFound, fmt.Errorf("%w: %s", internal.ErrProfileNotFound, name))
+		}
+		if !internal.AssumeYes() {
+			if !internal.CanPrompt() {
+				internal.Error("Cannot prompt for profile removal confirmation", internal.ErrNoInput, nil)
+				return exitErrorf(ExitUsage, "refusing to remove profile %s without confirmation. Re-run with --yes", name)
+			}
+			if !internal.YesNoPrompt(fmt.Sprintf("Remove profile %s and its API token?", name), false) {
+				return exitErrorf(ExitError, "profile removal cancelled")
+			}
+		}
+
+		if err := internal.RemoveProfile(name); err != nil {
+			return err
+		}
+		internal.Info("Removed profile", map[string]interface{}{"profile": name})
+		statusln(ui.Success.Render(fmt.Sprintf("Removed profile %s", name)))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd, profileUseCmd, profileAddCmd, profileRemoveCmd)
+	profileAddCmd.Flags().StringVar(&profileBaseURL, "base-url", "", "API base URL (default "+internal.DefaultBaseURL+")")
+	profileAddCmd.Flags().StringArrayVar(&profileDefaults, "default", nil, "Set a flag default (flag=value); repeatable")
+}
+
+// parseProfileDefaults parses flag=value pairs
+func parseProfileDefaults(pairs []string) (map[string]string, error) {
+	defaults := map[string]string{}
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimPrefix(strings.TrimSpace(name), "--")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid default %q (expected flag=value)", pair)
+		}
+		defaults[name] = value
+	}
+	return defaults, nil
+}
+
+// applyProfile selects the profile given with --profile and sets the running
+// command's flags from the profile's defaults where they weren't given
+func applyProfile(cmd *cobra.Command) error {
+	if flag := cmd.Flags().Lookup("profile"); flag != nil && flag.Changed {
+		internal.SetProfileOverride(flag.Value.String())
+	}
+	profile, err := internal.ActiveProfile()
+	if err != nil {
+		// Profiles can still be managed when the selected one is missing
+		if errors.Is(err, internal.ErrProfileNotFound) && cmd.Parent() == profileCmd {
+			return nil
+		}
+		return withExitCode(ExitNotFound, err)
+	}
+
+	return applyFlagDefaults(cmd, profile.Defaults, "profile "+profile.Name)
+}
+
+// profileListing is the machine readable result of profile list
+type profileListing struct {
+	Active   string        `json:"active" yaml:"active"`
+	Profiles []profileView `json:"profiles" yaml:"profiles"`
+}
+
+func (l profileListing) items() []interface{} {
+	values := make([]interface{}, len(l.Profiles))
+	for i, profile := range l.Profiles {
+		values[i] = profile
+	}
+	return values
+}
+
+// profileView is the stable representation of a profile. The token itself is
+// never shown.
+type profileView struct {
+	Name        string            `json:"name" yaml:"name"`
+	BaseURL     string            `json:"base_url" yaml:"base_url"`
+	Active      bool              `json:"active" yaml:"active"`
+	LoggedIn    bool              `json:"logged_in" yaml:"logged_in"` // Only known for plaintext tokens
+	Credentials string            `json:"credentials" yaml:"credentials"`
+	Defaults    map[string]string `json:"defaults,omitempty" yaml:"defaults,omitempty"`
+}
+
+func newProfileView(profile internal.Profile, active string) profileView {
+	return profileView{
+		Name:        profile.Name,
+		BaseURL:     profile.BaseURL,
+		Active:      profile.Name == active,
+		LoggedIn:    profile.APIKey != "",
+		Credentials: profile.CredentialHelperName(),
+		Defaults:    profile.Defaults,
+	}
+}
@@ -6,58 +6,165 @@ import (
 	"snippetkit/internal"
 	"strings"
 
-	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-var (
-	titleStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#ea580c")) // Orange
-	labelStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#fbbf24"))            // Light Orange
-	successStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#228B22"))            // Lightish Dark Green
-	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#ef4444"))            // Red
-	warningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#f59e0b"))            // Yellow
-	divider      = lipgloss.NewStyle().Foreground(lipgloss.Color("#444")).Render(strings.Repeat("─", 40))
-	infoStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#6b7280"))                 // Gray
-	urlStyle     = lipgloss.NewStyle().Underline(true).Foreground(lipgloss.Color("#3b82f6")) // Blue
-)
-
 var rootCmd = &cobra.Command{
 	Use:   "snippetkit",
 	Short: "SnippetKit - Easily manage reusable code snippets",
-	Long:  `SnippetKit CLI allows you to search, add, and manage code snippets.`,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	Long:  "SnippetKit CLI allows you to search, add, and manage code snippets.\n\n" + envHelp + "\n\n" + exitCodesHelp,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		format, err := parseOutputFormat(viper.GetString("output"))
+		if err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		// --json is kept as a shorthand for --output json
+		if flag := cmd.Flags().Lookup("json"); flag != nil && flag.Changed && flag.Value.String() == "true" {
+			format = outputFormat{Name: formatJSON}
+		}
+		output = format
+		// Keep machine readable and quiet output free of decoration
+		internal.SetProgressEnabled(!output.machine() && !viper.GetBool("quiet"))
+
+		// Move files from ~/.config/snippetkit before the config is read,
+		// unless a config file is given, e.g. to try out a separate setup
+		var migrated []internal.Migration
+		var migrateErr error
+		if viper.GetString("config") == "" {
+			migrated, migrateErr = internal.MigrateLegacyPaths()
+		}
 		internal.LoadConfig() // Load config before executing commands
 		internal.InitLogger()
+		for _, move := range migrated {
+			internal.Info("Moved to XDG directory", map[string]interface{}{"from": move.From, "to": move.To})
+			statusln(ui.Info.Render(fmt.Sprintf("Moved %s to %s", move.From, move.To)))
+		}
+		if migrateErr != nil {
+			internal.Warn("Failed to move files to XDG directories", map[string]interface{}{"error": migrateErr.Error()})
+			statusln(ui.Warning.Render("Warning: " + migrateErr.Error()))
+		}
+		if skipped := internal.SkippedProjectSettings(); len(skipped) > 0 {
+			internal.Warn("Ignored settings of the project config", map[string]interface{}{"file": internal.ProjectConfigFile(), "keys": skipped})
+			statusln(ui.Warning.Render(fmt.Sprintf("Warning: ignoring %s in %s, only the user config can set them", strings.Join(skipped, ", "), internal.ProjectConfigFile())))
+		}
+		// Flag defaults: the command's settings, then the profile's
+		if err := applyCommandDefaults(cmd); err != nil {
+			return err
+		}
+		if err := applyProfile(cmd); err != nil {
+			return err
+		}
 
-		loggingEnabled := viper.GetBool("logging_enabled")
-
-		if loggingEnabled {
-			fmt.Println(successStyle.Render("Logging enabled"))
-		} else {
-			fmt.Println(warningStyle.Render("Logging disabled"))
+		// Colors depend on the terminal, NO_COLOR and --no-color
+		internal.ApplyColorProfile()
+		if !output.machine() {
+			// Machine output isn't styled, so the terminal isn't asked for
+			// its background
+			applyUITheme()
 		}
+		return nil
 	},
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(infoStyle.Render(fmt.Sprintf("CLI v%s", internal.GetVersion())))
-		cmd.Help() // Display the help command
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fmt.Fprintln(internal.Stdout, ui.Info.Render(fmt.Sprintf("CLI v%s", internal.GetVersion())))
+		return cmd.Help() // Display the help command
 	},
 	Version: internal.GetVersion(),
+	// Errors are printed once by Execute
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+// envHelp documents the environment variables in --help
+const envHelp = `Environment:
+  SNIPPETKIT_API_KEY   API token, overriding the active profile's (e.g. in CI)
+  SNIPPETKIT_BASE_URL  API base URL, overriding the active profile's
+  SNIPPETKIT_PROFILE   Profile to use instead of the current one
+  SNIPPETKIT_CONFIG    Config file to use instead of the user config
+  SNIPPETKIT_<SETTING> Any other setting, e.g. SNIPPETKIT_LOG_LEVEL or
+                       SNIPPETKIT_CACHE_TTL (see 'snippetkit config list --all')`
+
+// statusf prints a human status message to stderr unless output is quiet.
+// Results go to stdout; everything else goes through here.
+func statusf(format string, args ...interface{}) {
+	if !internal.ProgressEnabled() {
+		return
+	}
+	fmt.Fprintf(internal.Stderr, format, args...)
+}
+
+// statusln is like statusf but prints its arguments as a line
+func statusln(args ...interface{}) {
+	if !internal.ProgressEnabled() {
+		return
+	}
+	fmt.Fprintln(internal.Stderr, args...)
+}
+
+// requireAPIKey loads and verifies the API token, showing progress while the
+// token is checked
+func requireAPIKey() (string, error) {
+	checkSpinner := internal.NewSpinner()
+	checkSpinner.Start("Checking auth status...")
+	// Load API token from config file
+	apiToken, err := internal.GetAPIKey()
+	if err != nil {
+		checkSpinner.Error("Failed to authenticate")
+		internal.Error("Failed to get API key", err, nil)
+		return "", err
+	}
+
+	checkSpinner.Success("Authenticated successfully")
+	return apiToken, nil
 }
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		printError(err)
+		os.Exit(exitCode(err))
 	}
 }
 
 func init() {
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(ExitUsage, err)
+	})
+
+	// Settings can be given as SNIPPETKIT_ environment variables
+	internal.ConfigureEnv()
+
 	// Global Persistent Flags
-	rootCmd.PersistentFlags().StringP("config", "c", "", "Specify config file (default is $HOME/.snippetkit/config.yaml)")
-	viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config"))
+	rootCmd.PersistentFlags().StringP("config", "c", "", "Specify config file (default is $XDG_CONFIG_HOME/snippetkit/config.yaml)")
+	internal.BindFlag("config", rootCmd.PersistentFlags().Lookup("config"))
+
+	// Auth profile, also selected with SNIPPETKIT_PROFILE
+	rootCmd.PersistentFlags().String("profile", "", "Use this auth profile instead of the current one")
 
 	// Add logging flag
 	rootCmd.PersistentFlags().Bool("logging", true, "Enable or disable logging")
-	viper.BindPFlag("logging_enabled", rootCmd.PersistentFlags().Lookup("logging"))
+	internal.BindFlag("logging_enabled", rootCmd.PersistentFlags().Lookup("logging"))
+
+	// Diagnostics
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Suppress spinners and status messages; only print results")
+	internal.BindFlag("quiet", rootCmd.PersistentFlags().Lookup("quiet"))
+	rootCmd.PersistentFlags().Bool("verbose", false, "Echo log lines to stderr")
+	internal.BindFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
+
+	// Serve snippets from the local cache only
+	rootCmd.PersistentFlags().Bool("offline", false, "Serve snippets strictly from the local cache")
+	internal.BindFlag("offline", rootCmd.PersistentFlags().Lookup("offline"))
+
+	// Colors
+	rootCmd.PersistentFlags().Bool("no-color", false, "Disable colors (also set by NO_COLOR)")
+	internal.BindFlag("no_color", rootCmd.PersistentFlags().Lookup("no-color"))
+
+	// Output format for results
+	rootCmd.PersistentFlags().StringP("output", "o", formatTable, "Output format: table, json, yaml or template='{{.ShortID}} {{.Title}}'")
+	internal.BindFlag("output", rootCmd.PersistentFlags().Lookup("output"))
+
+	// Non-interactive flags for CI and scripts
+	rootCmd.PersistentFlags().BoolP("yes", "y", false, "Assume yes for confirmations and accept defaults for all prompts")
+	internal.BindFlag("yes", rootCmd.PersistentFlags().Lookup("yes"))
+	rootCmd.PersistentFlags().Bool("no-input", false, "Never prompt for input; fail if a required value is missing")
+	internal.BindFlag("no_input", rootCmd.PersistentFlags().Lookup("no-input"))
 }
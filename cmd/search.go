@@ -2,29 +2,70 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"snippetkit/internal"
+	"strings"
 	"sync"
 
-	"github.com/leaanthony/spinner"
 	"github.com/spf13/cobra"
 )
 
 // Flags
 var (
-	langFilter string
-	tagFilter  string
-	limit      int
+	langFilter  string
+	tagFilter   string
+	limit       int
+	interactive bool
+	localSearch bool
+	sortOrder   string
+	searchPage  int
+	searchAll   bool
+
+	searchPreview bool
 )
 
 // searchCmd represents the search command
 var searchCmd = &cobra.Command{
 	Use:   "search [query]",
 	Short: "Search for snippets by name, language, or tags",
-	Long:  "Search for snippets in the SnippetKit repository based on name, programming language, or associated tags.",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		query := args[0]
-		searchWithSpinner(query)
+	Long: `Search for snippets in the SnippetKit repository based on name, programming language, or associated tags.
+
+The query supports filters, exclusions and exact phrases:
+
+  auth middleware lang:go tag:http -tag:deprecated "exact phrase" path:handlers/
+
+  lang:go,ts        snippets in any of the languages
+  tag:a,b tag:c     (a OR b) AND c
+  path:handlers/    the install path contains handlers/
+  "some phrase"     the title, description or code contains the phrase
+  -word, -tag:x     exclude a word, phrase or filter
+
+Filters the API can't express are applied to the results client side. Sorting
+by updated or title fetches every result first, so the pages are in one order.`,
+	Example: `  snippetkit search auth middleware lang:go tag:http -tag:deprecated
+  snippetkit search '"use client"' tag:ui,shadcnui --sort title`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query := strings.Join(args, " ")
+		if !internal.ValidSort(sortOrder) {
+			return exitErrorf(ExitUsage, "unknown sort order %q (expected relevance, updated or title)", sortOrder)
+		}
+		if searchPage < 1 {
+			return exitErrorf(ExitUsage, "--page must be at least 1")
+		}
+		if limit < 1 {
+			return exitErrorf(ExitUsage, "--limit must be at least 1")
+		}
+		if searchAll && cmd.Flags().Changed("page") {
+			return exitErrorf(ExitUsage, "--page and --all can't be used together")
+		}
+		if interactive {
+			return runBrowser(query)
+		}
+		if query == "" && langFilter == "" && tagFilter == "" {
+			return exitErrorf(ExitUsage, "a search query is required (or use --interactive)")
+		}
+		return searchWithSpinner(query)
 	},
 }
 
@@ -34,69 +75,244 @@ func init() {
 	// Define search flags
 	searchCmd.Flags().StringVarP(&langFilter, "lang", "l", "", "Filter snippets by programming language (e.g., typescript)")
 	searchCmd.Flags().StringVarP(&tagFilter, "tag", "t", "", "Filter snippets by tag (e.g., ui, shadcnui)")
-	searchCmd.Flags().IntVarP(&limit, "limit", "n", 10, "Limit the number of search results")
+	searchCmd.Flags().IntVarP(&limit, "limit", "n", 10, "Limit the number of search results per page")
+	searchCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Browse results in an interactive full-screen view")
+	searchCmd.Flags().BoolVar(&localSearch, "local", false, "Search the local index of cached and local snippets instead of the API")
+	searchCmd.Flags().StringVar(&sortOrder, "sort", internal.SortRelevance, "Sort results by relevance, updated or title")
+	searchCmd.Flags().IntVar(&searchPage, "page", 1, "Show this page of results")
+	searchCmd.Flags().BoolVar(&searchPreview, "preview", false, "Show the first line of code of each result")
+	searchCmd.Flags().BoolVar(&searchAll, "all", false, "Stream every result, fetching --limit results at a time")
 }
 
 // searchWithSpinner runs the search command with a spinner
-func searchWithSpinner(query string) {
+func searchWithSpinner(query string) error {
+	q, err := internal.ParseQuery(query)
+	if err != nil {
+		return withExitCode(ExitUsage, err)
+	}
+	q.AddLang(langFilter)
+	q.AddTag(tagFilter)
+
+	apiToken := ""
+	if !localSearch {
+		// The local index needs no authentication
+		if apiToken, err = requireAPIKey(); err != nil {
+			return err
+		}
+	}
+	if searchAll {
+		return searchAllResults(query, q, apiToken)
+	}
+
+	fetch := func(page int) (*internal.SearchPage, error) {
+		return fetchSearchPage(q, page, apiToken)
+	}
+	if sortOrder != "" && sortOrder != internal.SortRelevance {
+		// Sorting each page on its own would mix up the order across pages, so
+		// every result is fetched and sorted before it is paged
+		sorted, err := fetchSortedResults(q, apiToken)
+		if err != nil {
+			return err
+		}
+		fetch = func(page int) (*internal.SearchPage, error) {
+			return internal.PageOf(sorted, page, limit), nil
+		}
+	}
+
+	for page := searchPage; ; page++ {
+		result, err := fetch(page)
+		if err != nil {
+			return err
+		}
+
+		if output.machine() {
+			return writeResult(os.Stdout, schemaSearch, newSearchResult(query, result.Snippets, newPageInfo(page, result)))
+		}
+
+		// Display results
+		if len(result.Snippets) == 0 {
+			if page == 1 {
+				statusln(ui.Info.Render("\n No snippets found for query: ") + ui.Label.Render(query))
+			} else {
+				statusln(ui.Info.Render(fmt.Sprintf("\n No results on page %d", page)))
+			}
+			return nil
+		}
+		printSearchTable(result.Snippets, apiToken)
+
+		// Fetch more on demand when someone is watching
+		if !result.HasMore {
+			break
+		}
+		// --yes confirms actions; it doesn't page through every result
+		if !internal.CanPrompt() || internal.AssumeYes() || !internal.YesNoPrompt("Show more results?", false) {
+			statusln(ui.Info.Render(fmt.Sprintf("More results: add --page %d or --all", page+1)))
+			break
+		}
+	}
+
+	statusln()
+	statusln(ui.Info.Render("To install a snippet, run:"))
+	statusln("   " + ui.Info.Render("snippetkit add <snippet_id>"))
+	return nil
+}
+
+// fetchSearchPage fetches one page of results with a spinner
+func fetchSearchPage(q *internal.Query, page int, apiToken string) (*internal.SearchPage, error) {
 	var wg sync.WaitGroup
+	var result *internal.SearchPage
+	var searchErr error
 	wg.Add(1)
 
-	// Start spinner
-
 	// Run search in a separate goroutine
 	go func() {
 		defer wg.Done()
 
-		checkSpinner := spinner.New()
-		checkSpinner.Start("Checking auth status...")
-		// Load API token from config file
-		apiToken, err := internal.GetAPIKey()
+		var err error
+		myspinner := internal.NewSpinner()
+		if localSearch {
+			myspinner.Start("Searching local snippets...")
+			result, err = internal.SearchLocal(q, page, limit)
+		} else {
+			myspinner.Start("Searching for snippets...")
+			result, err = internal.SearchRemote(q, page, limit, apiToken)
+		}
 		if err != nil {
-			checkSpinner.Error("Failed to authenticate")
-			fmt.Print("Error authenticating. Run 'snippetkit login' to authenticate.")
-			internal.Error("Failed to get API key", err, nil)
+			internal.Error("Error searching snippets", err, nil)
+			myspinner.Error("Failed to fetch search results.")
+			searchErr = fmt.Errorf("failed to search snippets: %w", err)
 			return
 		}
+		myspinner.Success("Search completed successfully")
+	}()
 
-		checkSpinner.Success("Authenticated successfully")
+	// Wait for the search to complete
+	wg.Wait()
+	return result, searchErr
+}
 
-		myspinner := spinner.New()
+// fetchSortedResults fetches every result of a query with a spinner and sorts
+// them by --sort
+func fetchSortedResults(q *internal.Query, apiToken string) ([]internal.Snippet, error) {
+	var snippets []internal.Snippet
+	var err error
+	myspinner := internal.NewSpinner()
+	if localSearch {
+		myspinner.Start("Searching local snippets...")
+		var result *internal.SearchPage
+		if result, err = internal.SearchLocal(q, 1, 0); err == nil {
+			snippets = result.Snippets
+		}
+	} else {
 		myspinner.Start("Searching for snippets...")
-		// Fetch search results
-		snippets, err := internal.SearchSnippets(query, langFilter, tagFilter, limit, apiToken)
+		snippets, err = internal.SearchRemoteAll(q, apiToken)
+	}
+	if err != nil {
+		internal.Error("Error searching snippets", err, nil)
+		myspinner.Error("Failed to fetch search results.")
+		return nil, fmt.Errorf("failed to search snippets: %w", err)
+	}
+	myspinner.Success("Search completed successfully")
+	internal.SortSnippets(snippets, sortOrder)
+	return snippets, nil
+}
+
+// searchAllResults streams every result of a query, fetching pages of --limit
+// results as they are needed. Results are only collected first when they have
+// to be sorted, laid out in a table or wrapped in a single JSON or YAML
+// document.
+func searchAllResults(query string, q *internal.Query, apiToken string) error {
+	var next func() (internal.Snippet, bool)
+	var iterErr func() error
+	if localSearch {
+		result, err := internal.SearchLocal(q, 1, 0)
 		if err != nil {
-			internal.Error("Error searching snippets", err, nil)
-			myspinner.Error("Failed to fetch search results.")
-			return
+			return fmt.Errorf("failed to search snippets: %w", err)
 		}
+		i := 0
+		next = func() (internal.Snippet, bool) {
+			if i >= len(result.Snippets) {
+				return internal.Snippet{}, false
+			}
+			i++
+			return result.Snippets[i-1], true
+		}
+		iterErr = func() error { return nil }
+	} else {
+		it := q.Iterate(0, limit, apiToken)
+		next, iterErr = it.Next, it.Err
+	}
 
-		myspinner.Success("Search completed successfully")
-
-		// Display results
-		if len(snippets) == 0 {
-			fmt.Println(infoStyle.Render("\n No snippets found for query: ") + labelStyle.Render(query))
-			return
+	collect := output.Name != formatTemplate || (sortOrder != "" && sortOrder != internal.SortRelevance)
+	var snippets []internal.Snippet
+	count := 0
+	for snippet, ok := next(); ok; snippet, ok = next() {
+		count++
+		if collect {
+			snippets = append(snippets, snippet)
+		} else if err := writeResult(os.Stdout, schemaSearch, newSnippetView(&snippet, false)); err != nil {
+			return err
 		}
+	}
+	if err := iterErr(); err != nil {
+		internal.Error("Error searching snippets", err, nil)
+		return fmt.Errorf("failed to search snippets: %w", err)
+	}
 
-		// Format search results
-		var output string
-		output += "\n" + titleStyle.Render("> Search Results:") + "\n"
-		for _, snippet := range snippets {
-			output += fmt.Sprintf(
-				"* %s  %s [%s]\n",
-				labelStyle.Render(snippet.ShortID),
-				snippet.Title,
-				snippet.Language,
-			)
+	if collect {
+		internal.SortSnippets(snippets, sortOrder)
+		if output.machine() {
+			info := &pageInfo{Page: 1, Limit: len(snippets), Total: len(snippets)}
+			return writeResult(os.Stdout, schemaSearch, newSearchResult(query, snippets, info))
 		}
+		if len(snippets) > 0 {
+			printSearchTable(snippets, apiToken)
+		}
+	}
 
-		output += "\n" + infoStyle.Render("To install a snippet, run:") + "\n"
-		output += "   " + infoStyle.Render("snippetkit add <snippet_id>") + "\n"
+	if count == 0 {
+		statusln(ui.Info.Render("\n No snippets found for query: ") + ui.Label.Render(query))
+	} else if !output.machine() {
+		statusln()
+		statusln(ui.Info.Render("To install a snippet, run:"))
+		statusln("   " + ui.Info.Render("snippetkit add <snippet_id>"))
+	}
+	return nil
+}
 
-		fmt.Println(output)
-	}()
+// printSearchTable prints a table of results followed by their facets
+func printSearchTable(snippets []internal.Snippet, apiToken string) {
+	if searchPreview {
+		// Search results may leave out the code the preview needs
+		for i := range snippets {
+			if snippets[i].Code != "" {
+				continue
+			}
+			if full, err := internal.FetchSnippet(snippets[i].ShortID, apiToken); err == nil {
+				snippets[i].Code = full.Code
+			}
+		}
+	}
+	fmt.Fprintln(internal.Stdout, "\n"+ui.Title.Render("> Search Results:"))
+	fmt.Fprintln(internal.Stdout, renderSearchTable(snippets, searchPreview))
+	if facets := facetSummary(snippets); facets != "" {
+		fmt.Fprintln(internal.Stdout, "\n"+ui.Label.Render("Refine: ")+ui.Info.Render(facets))
+	}
+}
 
-	// Wait for the search to complete
-	wg.Wait()
+// newSearchResult builds the machine readable result of a search
+func newSearchResult(query string, snippets []internal.Snippet, page *pageInfo) searchResult {
+	result := searchResult{
+		Query:   query,
+		Lang:    langFilter,
+		Tag:     tagFilter,
+		Sort:    sortOrder,
+		Count:   len(snippets),
+		Page:    page,
+		Results: make([]snippetView, 0, len(snippets)),
+	}
+	for i := range snippets {
+		result.Results = append(result.Results, newSnippetView(&snippets[i], false))
+	}
+	return result
 }
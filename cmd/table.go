@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"snippetkit/internal"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// maxFacets caps how many values of a facet are listed
+const maxFacets = 8
+
+// minDescriptionWidth is the narrowest the description column is truncated
+// to before it is dropped
+const minDescriptionWidth = 20
+
+// searchColumn is a column of the search result table
+type searchColumn struct {
+	header string
+	value  func(snippet internal.Snippet) string
+}
+
+var (
+	idColumn          = searchColumn{"ID", func(s internal.Snippet) string { return s.ShortID }}
+	titleColumn       = searchColumn{"TITLE", func(s internal.Snippet) string { return s.Title }}
+	languageColumn    = searchColumn{"LANGUAGE", func(s internal.Snippet) string { return s.Language }}
+	tagsColumn        = searchColumn{"TAGS", func(s internal.Snippet) string { return strings.Join(s.Tags, ", ") }}
+	descriptionColumn = searchColumn{"DESCRIPTION", func(s internal.Snippet) string { return oneLine(s.Description) }}
+	previewColumn     = searchColumn{"PREVIEW", func(s internal.Snippet) string { return firstCodeLine(s.Code) }}
+)
+
+// renderSearchTable renders search results as a table that fits the terminal.
+// With preview set a column shows the first line of each snippet's code. Long
+// descriptions are truncated to the width the other columns leave; on
+// terminals too narrow for that the description and tags are dropped before
+// the remaining columns are truncated, so IDs stay readable.
+func renderSearchTable(snippets []internal.Snippet, preview bool) string {
+	width, _ := internal.TerminalSize()
+	withPreview := func(columns ...searchColumn) []searchColumn {
+		if preview {
+			columns = append(columns, previewColumn)
+		}
+		return columns
+	}
+
+	full := newSearchTable(snippets, withPreview(idColumn, titleColumn, languageColumn, tagsColumn, descriptionColumn))
+	if rendered := full.Render(); lipgloss.Width(rendered) <= width {
+		return rendered
+	}
+
+	// Truncate the descriptions to the width left by the other columns, less
+	// the padding of the description column
+	rest := newSearchTable(snippets, withPreview(idColumn, titleColumn, languageColumn, tagsColumn)).Render()
+	if left := width - lipgloss.Width(rest) - 2; left >= minDescriptionWidth {
+		truncated := searchColumn{descriptionColumn.header, func(s internal.Snippet) string {
+			return ansi.Truncate(descriptionColumn.value(s), left, "…")
+		}}
+		return newSearchTable(snippets, withPreview(idColumn, titleColumn, languageColumn, tagsColumn, truncated)).Render()
+	}
+
+	layouts := [][]searchColumn{
+		withPreview(idColumn, titleColumn, languageColumn, tagsColumn),
+		withPreview(idColumn, titleColumn, languageColumn),
+	}
+	var t *table.Table
+	for _, columns := range layouts {
+		t = newSearchTable(snippets, columns)
+		if rendered := t.Render(); lipgloss.Width(rendered) <= width {
+			return rendered
+		}
+	}
+	return t.Width(width).Render()
+}
+
+func newSearchTable(snippets []internal.Snippet, columns []searchColumn) *table.Table {
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.header
+	}
+	rows := make([][]string, 0, len(snippets))
+	for _, snippet := range snippets {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = column.value(snippet)
+		}
+		rows = append(rows, row)
+	}
+
+	cell := lipgloss.NewStyle().PaddingRight(2)
+	return table.New().
+		Headers(headers...).
+		Rows(rows...).
+		Border(lipgloss.NormalBorder()).
+		BorderTop(false).
+		BorderBottom(false).
+		BorderLeft(false).
+		BorderRight(false).
+		BorderColumn(false).
+		BorderStyle(ui.Info).
+		Wrap(false).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			switch {
+			case row == table.HeaderRow, col == 0:
+				return cell.Inherit(ui.Label)
+			case col >= 3:
+				return cell.Inherit(ui.Info)
+			}
+			return cell
+		})
+}
+
+// facetSummary counts the languages and tags of the results, most common
+// first, so the query can be refined, e.g. "go: 12, typescript: 8 / tags: http 5"
+func facetSummary(snippets []internal.Snippet) string {
+	languages := map[string]int{}
+	tags := map[string]int{}
+	for _, snippet := range snippets {
+		if snippet.Language != "" {
+			languages[strings.ToLower(snippet.Language)]++
+		}
+		for _, tag := range snippet.Tags {
+			tags[strings.ToLower(tag)]++
+		}
+	}
+
+	var parts []string
+	if len(languages) > 0 {
+		var counts []string
+		for _, facet := range topFacets(languages) {
+			counts = append(counts, fmt.Sprintf("%s: %d", facet, languages[facet]))
+		}
+		parts = append(parts, strings.Join(counts, ", "))
+	}
+	if len(tags) > 0 {
+		var counts []string
+		for _, facet := range topFacets(tags) {
+			counts = append(counts, fmt.Sprintf("%s %d", facet, tags[facet]))
+		}
+		parts = append(parts, "tags: "+strings.Join(counts, ", "))
+	}
+	return strings.Join(parts, " / ")
+}
+
+// topFacets returns the most common values of a facet
+func topFacets(counts map[string]int) []string {
+	values := make([]string, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] != counts[values[j]] {
+			return counts[values[i]] > counts[values[j]]
+		}
+		return values[i] < values[j]
+	})
+	if len(values) > maxFacets {
+		values = values[:maxFacets]
+	}
+	return values
+}
+
+// firstCodeLine returns the first non-blank line of code
+func firstCodeLine(code string) string {
+	for _, line := range strings.Split(code, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// oneLine joins the lines of s so it fits in a table cell
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"snippetkit/internal"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestRenderSearchTableTruncatesDescriptions(t *testing.T) {
+	snippets := []internal.Snippet{{
+		ShortID:     "abc123",
+		Title:       "Retry middleware",
+		Language:    "go",
+		Tags:        []string{"http", "resilience"},
+		Description: strings.Repeat("Retries failed requests with exponential backoff. ", 4),
+	}}
+
+	tests := []struct {
+		width       int
+		description bool
+	}{
+		{300, true},
+		{100, true},
+		{60, false}, // Too narrow for a useful description
+	}
+	for _, tt := range tests {
+		t.Setenv("COLUMNS", strconv.Itoa(tt.width))
+		rendered := renderSearchTable(snippets, false)
+		for _, line := range strings.Split(rendered, "\n") {
+			if w := lipgloss.Width(line); w > tt.width {
+				t.Errorf("width %d: line is %d wide: %q", tt.width, w, line)
+			}
+		}
+		if got := strings.Contains(rendered, "DESCRIPTION"); got != tt.description {
+			t.Errorf("width %d: description column shown = %v, want %v", tt.width, got, tt.description)
+		}
+		if tt.width == 100 && !strings.Contains(rendered, "…") {
+			t.Errorf("width %d: description isn't truncated with an ellipsis:\n%s", tt.width, rendered)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"snippetkit/internal"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// uiStyles are the lipgloss styles of a UI theme
+type uiStyles struct {
+	Title   lipgloss.Style
+	Label   lipgloss.Style
+	Success lipgloss.Style
+	Error   lipgloss.Style
+	Warning lipgloss.Style
+	Info    lipgloss.Style
+	URL     lipgloss.Style
+	Rule    lipgloss.Style
+}
+
+// ui renders all command output. It starts with the dark theme so early
+// errors are styled, and is replaced by the configured theme before commands
+// run.
+var ui = newUIStyles(internal.ActiveUITheme())
+
+func newUIStyles(theme *internal.UITheme) *uiStyles {
+	return &uiStyles{
+		Title:   theme.Style(theme.Title).Bold(true),
+		Label:   theme.Style(theme.Label),
+		Success: theme.Style(theme.Success),
+		Error:   theme.Style(theme.Error),
+		Warning: theme.Style(theme.Warning),
+		Info:    theme.Style(theme.Info),
+		URL:     theme.Style(theme.URL).Underline(true),
+		Rule:    theme.Style(theme.Divider),
+	}
+}
+
+// Divider returns a horizontal rule separating sections of output
+func (s *uiStyles) Divider() string {
+	return s.Rule.Render(strings.Repeat("─", 40))
+}
+
+// applyUITheme loads the configured UI theme, falling back to the dark theme
+// when the configuration is invalid
+func applyUITheme() {
+	theme, err := internal.LoadUITheme()
+	if err != nil {
+		internal.Warn("Invalid UI theme, using dark", map[string]interface{}{"error": err.Error()})
+		statusln(ui.Warning.Render("Warning: " + err.Error()))
+		theme, _ = internal.ResolveUITheme("dark")
+	}
+	internal.SetUITheme(theme)
+	ui = newUIStyles(theme)
+}
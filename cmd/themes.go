@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"snippetkit/internal"
+
+	"github.com/spf13/cobra"
+)
+
+var themesList bool
+var themesUI bool
+
+// themeSample is the code the themes are previewed with
+const themeSample = `// Greet returns a greeting for name
+func Greet(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+	return fmt.Sprintf("Hello, %s! You are #%d", name, 1), nil
+}`
+
+// themesCmd lists and previews the syntax highlighting themes
+var themesCmd = &cobra.Command{
+	Use:   "themes [theme]",
+	Short: "List and preview syntax highlighting themes",
+	Long: `List the syntax highlighting themes with a preview of each, or preview a
+single theme. Select a theme with 'theme: <name>' in the config file.
+
+With --ui the themes of the CLI's own output are listed instead. Select one
+with 'ui_theme: <name>'; the default, auto, picks dark or light from the
+terminal background. Themes can be defined in the config file, starting from
+a built-in base theme (dark when base is left out):
+
+  ui_theme: mine
+  ui_themes:
+    mine:
+      base: light
+      title: "#9333ea"
+      label: "#0e7490"`,
+	Args: usageArgs(cobra.MaximumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if themesUI {
+			return listUIThemes(args)
+		}
+
+		themes := internal.Themes()
+		current := internal.Theme()
+
+		if len(args) == 1 {
+			if !containsString(themes, args[0]) {
+				return exitErrorf(ExitNotFound, "unknown theme %q (run 'snippetkit themes --list' to see them)", args[0])
+			}
+			themes = args[:1]
+		}
+
+		if output.machine() {
+			return writeResult(os.Stdout, schemaThemes, themeListing{Current: current, Themes: themes})
+		}
+
+		for _, theme := range themes {
+			name := theme
+			if theme == current {
+				name += " (current)"
+			}
+			if themesList {
+				fmt.Fprintln(internal.Stdout, name)
+				continue
+			}
+			fmt.Fprintln(internal.Stdout, ui.Title.Render(name))
+			fmt.Fprintln(internal.Stdout, ui.Divider())
+			fmt.Fprintln(internal.Stdout, highlightCodeWith(themeSample, "go", theme))
+			fmt.Fprintln(internal.Stdout)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(themesCmd)
+	themesCmd.Flags().BoolVarP(&themesList, "list", "l", false, "Only list the theme names")
+	themesCmd.Flags().BoolVar(&themesUI, "ui", false, "List the UI themes instead of the highlighting themes")
+}
+
+// listUIThemes lists or previews the UI themes
+func listUIThemes(args []string) error {
+	themes := internal.UIThemes()
+	current := internal.ActiveUITheme().Name
+	if len(args) == 1 {
+		if _, err := internal.ResolveUITheme(args[0]); err != nil {
+			return withExitCode(ExitNotFound, err)
+		}
+		themes = args[:1]
+	}
+
+	if output.machine() {
+		return writeResult(os.Stdout, schemaThemes, themeListing{Current: current, Themes: themes})
+	}
+
+	for _, name := range themes {
+		theme, err := internal.ResolveUITheme(name)
+		if err != nil {
+			return err
+		}
+		label := name
+		if name == current {
+			label += " (current)"
+		}
+		if themesList {
+			fmt.Fprintln(internal.Stdout, label)
+			continue
+		}
+
+		styles := newUIStyles(theme)
+		fmt.Fprintln(internal.Stdout, styles.Title.Render("> "+label))
+		fmt.Fprintln(internal.Stdout, styles.Divider())
+		fmt.Fprintln(internal.Stdout, styles.Label.Render("Title: ")+"Debounce hook")
+		fmt.Fprintln(internal.Stdout, styles.Success.Render("Snippet installed")+"  "+
+			styles.Warning.Render("File exists")+"  "+
+			styles.Error.Render("Error: not found"))
+		fmt.Fprintln(internal.Stdout, styles.Info.Render("Run snippetkit add <snippet_id>")+"  "+styles.URL.Render("https://snippetkit.vercel.app"))
+		fmt.Fprintln(internal.Stdout)
+	}
+	return nil
+}
+
+// themeListing is the machine readable result of themes
+type themeListing struct {
+	Current string   `json:"current" yaml:"current"`
+	Themes  []string `json:"themes" yaml:"themes"`
+}
+
+func (l themeListing) items() []interface{} {
+	values := make([]interface{}, len(l.Themes))
+	for i, theme := range l.Themes {
+		values[i] = theme
+	}
+	return values
+}
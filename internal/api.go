@@ -6,156 +6,251 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 // APIResponseSingle is used for FetchSnippet (returns a single snippet)
 type APIResponseSingle struct {
 	Success bool    `json:"success"`
+	Error   string  `json:"error,omitempty"`
 	Data    Snippet `json:"data"` // Expecting a single object
 }
 
 // APIResponseMultiple is used for SearchSnippets (returns multiple snippets)
 type APIResponseMultiple struct {
-	Success bool      `json:"success"`
-	Data    []Snippet `json:"data"` // Expecting an array
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	Data       []Snippet `json:"data"` // Expecting an array
+	Total      int       `json:"total,omitempty"`
+	HasMore    *bool     `json:"hasMore,omitempty"`
+	NextCursor string    `json:"nextCursor,omitempty"`
+}
+
+// SearchOptions are the parameters of a search request. Cursor, when set,
+// takes precedence over Offset.
+type SearchOptions struct {
+	Query  string
+	Lang   string
+	Tag    string
+	Limit  int
+	Offset int
+	Cursor string
+}
+
+// SearchPage is one page of search results
+type SearchPage struct {
+	Snippets   []Snippet
+	Offset     int
+	Limit      int
+	Total      int // Zero when the API doesn't report it
+	NextCursor string
+	HasMore    bool
 }
 
 // Snippet represents a single snippet
 type Snippet struct {
-	ID          string   `json:"_id"`
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
-	Language    string   `json:"language"`
-	ShortID     string   `json:"shortId"`
-	Code        string   `json:"code"`
-	Path        string   `json:"path"`
-	Tags        []string `json:"tags"`
+	ID          string    `json:"_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Language    string    `json:"language"`
+	ShortID     string    `json:"shortId"`
+	Code        string    `json:"code"`
+	Path        string    `json:"path"`
+	Tags        []string  `json:"tags"`
+	UpdatedAt   time.Time `json:"updatedAt"`
 }
 
-// FetchSnippet fetches a single snippet from the API
-func FetchSnippet(snippetID string, apiKey string) (*Snippet, error) {
-	apiURL := fmt.Sprintf("https://snippetkit.vercel.app/api/snippet/get/%s", snippetID)
+// apiResponse is the body of a successful API request
+type apiResponse struct {
+	Body        []byte
+	ETag        string
+	NotModified bool // The cached copy identified by the request ETag is current
+}
 
+// apiGet performs an authenticated GET request and returns the response body.
+// When etag is set the request is conditional. Transport failures are returned
+// as *NetworkError and well known status codes are mapped to ErrUnauthorized
+// and ErrNotFound.
+func apiGet(apiURL string, apiKey string, etag string) (*apiResponse, error) {
 	req, err := http.NewRequest("GET", apiURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
 
 	req.Header.Set("x-api-key", apiKey)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
 
 	client := &http.Client{}
+	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to API: %v", err)
+		recordHTTP("GET", apiURL, start, 0, 0, err)
+		return nil, &NetworkError{Err: err}
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
+	recordHTTP("GET", apiURL, start, resp.StatusCode, len(body), err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read API response: %v", err)
+		return nil, &NetworkError{Err: fmt.Errorf("failed to read API response: %v", err)}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return &apiResponse{ETag: etag, NotModified: true}, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, ErrUnauthorized
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode >= 500:
+		return nil, &APIError{StatusCode: resp.StatusCode}
+	}
+
+	return &apiResponse{Body: body, ETag: resp.Header.Get("ETag")}, nil
+}
+
+// FetchSnippet fetches a single snippet from the API, going through the cache
+func FetchSnippet(snippetID string, apiKey string) (*Snippet, error) {
+	// Local snippets found by `search --local` are read from disk
+	if name, ok := strings.CutPrefix(snippetID, "local:"); ok {
+		return LoadLocalSnippet(filepath.Join(LocalSnippetsDir(), filepath.Base(name)))
 	}
 
+	apiURL := fmt.Sprintf("%s/api/snippet/get/%s", BaseURL(), url.PathEscape(snippetID))
+
 	var apiResp APIResponseSingle
-	err = json.Unmarshal(body, &apiResp)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse API response: %v", err)
+	decode := func(body []byte) (string, error) {
+		apiResp = APIResponseSingle{}
+		if err := json.Unmarshal(body, &apiResp); err != nil {
+			return "", fmt.Errorf("failed to parse API response: %v", err)
+		}
+		if !apiResp.Success {
+			return "", &APIError{StatusCode: http.StatusOK, Message: apiResp.Error}
+		}
+		return apiResp.Data.Title, nil
 	}
 
-	if !apiResp.Success {
-		return nil, fmt.Errorf("API response was unsuccessful")
+	body, err := cachedGet(snippetCacheKey(snippetID), apiURL, apiKey, decode)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decode(body); err != nil {
+		return nil, err
 	}
 
 	return &apiResp.Data, nil
 }
 
-// SearchSnippets searches for snippets by query, language, tag, and limit
-func SearchSnippets(query, lang, tag string, limit int, apiKey string) ([]Snippet, error) {
+// SearchSnippets requests a page of search results from the API
+func SearchSnippets(opts SearchOptions, apiKey string) (*SearchPage, error) {
 	// Build query parameters
 	params := url.Values{}
-	params.Add("q", query)
-	if lang != "" {
-		params.Add("lang", lang)
+	params.Add("q", opts.Query)
+	if opts.Lang != "" {
+		params.Add("lang", opts.Lang)
 	}
-	if tag != "" {
-		params.Add("tag", tag)
+	if opts.Tag != "" {
+		params.Add("tag", opts.Tag)
 	}
-	params.Add("limit", fmt.Sprintf("%d", limit))
-
-	apiURL := fmt.Sprintf("https://snippetkit.vercel.app/api/snippet/search?%s", params.Encode())
-
-	// Create an HTTP request
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+	params.Add("limit", fmt.Sprintf("%d", opts.Limit))
+	if opts.Cursor != "" {
+		params.Add("cursor", opts.Cursor)
+	} else if opts.Offset > 0 {
+		params.Add("offset", fmt.Sprintf("%d", opts.Offset))
 	}
 
-	req.Header.Set("x-api-key", apiKey)
+	apiURL := fmt.Sprintf("%s/api/snippet/search?%s", BaseURL(), params.Encode())
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to API: %v", err)
+	var searchResp APIResponseMultiple // Expecting an array in "data"
+	decode := func(body []byte) (string, error) {
+		searchResp = APIResponseMultiple{}
+		if err := json.Unmarshal(body, &searchResp); err != nil {
+			return "", fmt.Errorf("failed to parse API response: %v", err)
+		}
+		if !searchResp.Success {
+			return "", &APIError{StatusCode: http.StatusOK, Message: searchResp.Error}
+		}
+		return fmt.Sprintf("search %q (%d results)", opts.Query, len(searchResp.Data)), nil
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	// Encoded parameters are sorted, so equal searches share a cache key
+	body, err := cachedGet("search:"+params.Encode()+cacheScope(), apiURL, apiKey, decode)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read API response: %v", err)
+		return nil, err
 	}
-
-	var searchResp APIResponseMultiple // Expecting an array in "data"
-	err = json.Unmarshal(body, &searchResp)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse API response: %v", err)
+	if _, err := decode(body); err != nil {
+		return nil, err
 	}
 
-	if !searchResp.Success {
-		return nil, fmt.Errorf("API response was unsuccessful")
+	page := &SearchPage{
+		Snippets:   searchResp.Data,
+		Offset:     opts.Offset,
+		Limit:      opts.Limit,
+		Total:      searchResp.Total,
+		NextCursor: searchResp.NextCursor,
 	}
+	switch {
+	case searchResp.HasMore != nil:
+		page.HasMore = *searchResp.HasMore
+	case searchResp.NextCursor != "":
+		page.HasMore = true
+	case searchResp.Total > 0:
+		page.HasMore = opts.Offset+len(searchResp.Data) < searchResp.Total
+	default:
+		// Without paging metadata a full page means there may be more
+		page.HasMore = opts.Limit > 0 && len(searchResp.Data) >= opts.Limit
+	}
+	return page, nil
+}
 
-	return searchResp.Data, nil
+// TokenInfo describes an API token as reported by the verify endpoint. The
+// account, expiry and scopes are only known when the server reports them.
+type TokenInfo struct {
+	Name      string     `json:"name"`
+	Email     string     `json:"email"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Scopes    []string   `json:"scopes"`
 }
 
-// VerifyToken verifies the API key by calling the `/api/token/verify` endpoint
-func VerifyToken(apiKey string) (bool, error) {
-	apiURL := "https://snippetkit.vercel.app/api/token/verify"
+// InspectToken verifies the API key by calling the `/api/token/verify`
+// endpoint and returns what the server knows about it
+func InspectToken(apiKey string) (*TokenInfo, error) {
+	apiURL := BaseURL() + "/api/token/verify"
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+	resp, err := apiGet(apiURL, apiKey, "")
 	if err != nil {
-		return false, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	// Set API key in headers
-	req.Header.Set("x-api-key", apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return false, fmt.Errorf("failed to connect to API: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return false, fmt.Errorf("failed to read API response: %v", err)
+		return nil, err
 	}
 
 	// Parse the JSON response
 	var apiResp struct {
-		Success bool   `json:"success"`
-		Error   string `json:"error,omitempty"`
+		Success bool       `json:"success"`
+		Error   string     `json:"error,omitempty"`
+		Data    *TokenInfo `json:"data,omitempty"`
 	}
-	err = json.Unmarshal(body, &apiResp)
+	err = json.Unmarshal(resp.Body, &apiResp)
 	if err != nil {
-		return false, fmt.Errorf("failed to parse API response: %v", err)
+		return nil, fmt.Errorf("failed to parse API response: %v", err)
 	}
 
-	// If success is true, return true
-	if apiResp.Success {
-		return true, nil
+	// If not successful, return the error message
+	if !apiResp.Success {
+		return nil, fmt.Errorf("%w: %s", ErrUnauthorized, apiResp.Error)
 	}
+	if apiResp.Data == nil {
+		return &TokenInfo{}, nil
+	}
+	return apiResp.Data, nil
+}
 
-	// If not successful, return the error message
-	return false, fmt.Errorf("API key validation failed: %s", apiResp.Error)
-}
\ No newline at end of file
+// VerifyToken verifies the API key by calling the `/api/token/verify` endpoint
+func VerifyToken(apiKey string) (bool, error) {
+	if _, err := InspectToken(apiKey); err != nil {
+		return false, err
+	}
+	return true, nil
+}
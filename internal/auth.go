@@ -0,0 +1,277 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Browser login endpoints, relative to the profile's base URL
+const (
+	deviceCodePath  = "/api/auth/device/code"
+	deviceTokenPath = "/api/auth/device/token"
+	callbackPath    = "/cli/login"
+)
+
+// Polling limits of the device flow
+const (
+	defaultPollInterval = 5 * time.Second
+	maxPollInterval     = 60 * time.Second
+	defaultDeviceExpiry = 15 * time.Minute
+	callbackTimeout     = 5 * time.Minute
+	// authRequestTimeout caps each request to the login endpoints
+	authRequestTimeout = 30 * time.Second
+)
+
+var (
+	// ErrAuthDenied is returned when the user rejects a browser login
+	ErrAuthDenied = errors.New("login was denied in the browser")
+	// ErrAuthExpired is returned when a browser login isn't completed in time
+	ErrAuthExpired = errors.New("login code expired before it was approved")
+)
+
+// DeviceCode is a pending device authorization, see RFC 8628
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"` // Seconds
+	Interval                int    `json:"interval"`   // Seconds between polls
+}
+
+// deviceTokenResponse is the answer to a token poll
+type deviceTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// authPost posts a form to the active profile's API. The request is abandoned
+// when ctx is done or the server doesn't answer in time.
+func authPost(ctx context.Context, path string, form url.Values) (int, []byte, error) {
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, BaseURL()+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	client := &http.Client{Timeout: authRequestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		recordHTTP("POST", BaseURL()+path, start, 0, 0, err)
+		return 0, nil, &NetworkError{Err: err}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	recordHTTP("POST", BaseURL()+path, start, resp.StatusCode, len(body), err)
+	if err != nil {
+		return 0, nil, &NetworkError{Err: fmt.Errorf("failed to read API response: %v", err)}
+	}
+	return resp.StatusCode, body, nil
+}
+
+// RequestDeviceCode starts a device login
+func RequestDeviceCode(ctx context.Context) (*DeviceCode, error) {
+	status, body, err := authPost(ctx, deviceCodePath, url.Values{"client_id": {"snippetkit-cli"}})
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case status == http.StatusNotFound:
+		return nil, fmt.Errorf("this server doesn't support browser login. Run 'snippetkit login' with a token instead")
+	case status >= 500:
+		return nil, &APIError{StatusCode: status}
+	case status != http.StatusOK:
+		return nil, &APIError{StatusCode: status, Message: strings.TrimSpace(string(body))}
+	}
+
+	var code DeviceCode
+	if err := json.Unmarshal(body, &code); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %v", err)
+	}
+	if code.DeviceCode == "" || code.UserCode == "" || code.VerificationURI == "" {
+		return nil, fmt.Errorf("failed to parse API response: incomplete device code")
+	}
+	return &code, nil
+}
+
+// PollDeviceToken waits until the device login is approved and returns the
+// token. The server's interval is honored and raised on slow_down; network
+// errors back off exponentially until the code expires. A poll in flight is
+// abandoned when ctx is cancelled or the code expires.
+func PollDeviceToken(ctx context.Context, code *DeviceCode) (string, error) {
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	expiry := time.Duration(code.ExpiresIn) * time.Second
+	if expiry <= 0 {
+		expiry = defaultDeviceExpiry
+	}
+	deadline := time.Now().Add(expiry)
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	form := url.Values{
+		"client_id":   {"snippetkit-cli"},
+		"device_code": {code.DeviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+
+	wait := interval
+	for {
+		if time.Now().Add(wait).After(deadline) {
+			return "", ErrAuthExpired
+		}
+		select {
+		case <-ctx.Done():
+			return "", pollError(ctx, deadline)
+		case <-time.After(wait):
+		}
+
+		status, body, err := authPost(ctx, deviceTokenPath, form)
+		if ctx.Err() != nil {
+			return "", pollError(ctx, deadline)
+		}
+		if err != nil {
+			wait = min(wait*2, maxPollInterval)
+			Warn("Device login poll failed, backing off", map[string]interface{}{"error": err.Error(), "wait": wait.String()})
+			continue
+		}
+		if status >= 500 {
+			wait = min(wait*2, maxPollInterval)
+			continue
+		}
+		wait = interval
+
+		var resp deviceTokenResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return "", fmt.Errorf("failed to parse API response: %v", err)
+		}
+		switch resp.Error {
+		case "":
+			if resp.AccessToken == "" {
+				return "", fmt.Errorf("failed to parse API response: no access token")
+			}
+			return resp.AccessToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+			wait = interval
+		case "expired_token":
+			return "", ErrAuthExpired
+		case "access_denied":
+			return "", ErrAuthDenied
+		default:
+			msg := resp.Error
+			if resp.ErrorDescription != "" {
+				msg += ": " + resp.ErrorDescription
+			}
+			return "", &APIError{StatusCode: status, Message: msg}
+		}
+	}
+}
+
+// pollError is the error of a device login poll ended by ctx
+func pollError(ctx context.Context, deadline time.Time) error {
+	if !time.Now().Before(deadline) {
+		return ErrAuthExpired
+	}
+	return ctx.Err()
+}
+
+// CallbackLogin logs in through the browser with a redirect to a server on
+// localhost. open is called with the URL the user has to visit.
+func CallbackLogin(ctx context.Context, open func(loginURL string)) (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to listen for the login callback: %v", err)
+	}
+	defer listener.Close()
+
+	stateBytes := make([]byte, 16)
+	if _, err := rand.Read(stateBytes); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(stateBytes)
+	redirect := fmt.Sprintf("http://%s/callback", listener.Addr())
+
+	type result struct {
+		token string
+		err   error
+	}
+	results := make(chan result, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		// The state ties the redirect to this login and keeps other pages out
+		if query.Get("state") != state {
+			http.Error(w, "Invalid login state", http.StatusBadRequest)
+			return
+		}
+		var res result
+		switch {
+		case query.Get("error") == "access_denied":
+			res.err = ErrAuthDenied
+		case query.Get("error") != "":
+			res.err = fmt.Errorf("browser login failed: %s", query.Get("error"))
+		case query.Get("token") == "":
+			res.err = fmt.Errorf("browser login failed: no token in the callback")
+		default:
+			res.token = query.Get("token")
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if res.err != nil {
+			fmt.Fprint(w, "<html><body><h1>SnippetKit login failed</h1><p>Return to your terminal for details.</p></body></html>")
+		} else {
+			fmt.Fprint(w, "<html><body><h1>SnippetKit login complete</h1><p>You can close this window.</p></body></html>")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	defer server.Close()
+
+	params := url.Values{"redirect_uri": {redirect}, "state": {state}}
+	open(BaseURL() + callbackPath + "?" + params.Encode())
+
+	ctx, cancel := context.WithTimeout(ctx, callbackTimeout)
+	defer cancel()
+	select {
+	case res := <-results:
+		return res.token, res.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", ErrAuthExpired
+		}
+		return "", ctx.Err()
+	}
+}
+
+// OpenBrowser opens a URL in the default browser
+func OpenBrowser(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestPollDeviceTokenAbandonsHungRequests(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release // The token endpoint never answers
+	}))
+	defer server.Close()
+	defer close(release)
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("base_url", server.URL)
+
+	code := &DeviceCode{DeviceCode: "device", UserCode: "ABCD-EFGH", Interval: 1, ExpiresIn: 2}
+	start := time.Now()
+	if _, err := PollDeviceToken(context.Background(), code); !errors.Is(err, ErrAuthExpired) {
+		t.Errorf("PollDeviceToken() error = %v, want ErrAuthExpired", err)
+	}
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("PollDeviceToken() returned after %v, past the expiry", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	code.ExpiresIn = 60
+	if _, err := PollDeviceToken(ctx, code); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PollDeviceToken() with a cancelled context error = %v, want the context's error", err)
+	}
+}
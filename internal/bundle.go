@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// bundleDir is the directory the files of a bundle are extracted to
+	bundleDir = "snippetkit-debug"
+	// maxBundleLogSize caps each log file in a debug bundle; the end is kept
+	maxBundleLogSize = 1 << 20
+)
+
+// BundleFile is a file of a debug bundle
+type BundleFile struct {
+	Name string
+	Data []byte
+}
+
+// RecentLogFiles reads the newest n log files from the logs directory. Large
+// files are cut to their last lines.
+func RecentLogFiles(n int) ([]BundleFile, error) {
+	paths, err := filepath.Glob(filepath.Join(LogDir(), "snippetkit-*.log"))
+	if err != nil {
+		return nil, err
+	}
+	// The names carry the date, so they sort by age
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	if len(paths) > n {
+		paths = paths[:n]
+	}
+
+	files := make([]BundleFile, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read log file: %v", err)
+		}
+		if len(data) > maxBundleLogSize {
+			data = data[len(data)-maxBundleLogSize:]
+			if i := bytes.IndexByte(data, '\n'); i >= 0 {
+				data = data[i+1:]
+			}
+		}
+		files = append(files, BundleFile{Name: "logs/" + filepath.Base(path), Data: data})
+	}
+	return files, nil
+}
+
+// WriteBundle writes files to a gzipped tar archive, only readable by the user
+func WriteBundle(path string, files []BundleFile) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %v", err)
+	}
+	if err := writeBundle(out, files); err != nil {
+		out.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to write bundle: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to write bundle: %v", err)
+	}
+	return nil
+}
+
+func writeBundle(w io.Writer, files []BundleFile) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, file := range files {
+		header := &tar.Header{
+			Name:    bundleDir + "/" + file.Name,
+			Mode:    0600,
+			Size:    int64(len(file.Data)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.Data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// DefaultBundlePath returns the file name of a new debug bundle in the
+// current directory
+func DefaultBundlePath() string {
+	return fmt.Sprintf("%s-%s.tar.gz", bundleDir, time.Now().Format("20060102-150405"))
+}
@@ -0,0 +1,428 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultCacheTTL     = 24 * time.Hour
+	defaultCacheMaxSize = 50 << 20 // 50MB
+)
+
+// ErrOffline is returned when --offline is set and the data isn't cached
+var ErrOffline = errors.New("not available offline")
+
+// CacheEntry describes a cached API response. The response body is stored
+// once per content hash under objects/, so identical responses share storage.
+type CacheEntry struct {
+	Key        string    `json:"key"`
+	Label      string    `json:"label"`
+	Hash       string    `json:"hash"`
+	Size       int64     `json:"size"`
+	ETag       string    `json:"etag,omitempty"`
+	FetchedAt  time.Time `json:"fetched_at"`
+	AccessedAt time.Time `json:"accessed_at"`
+}
+
+// Fresh reports whether the entry is younger than ttl
+func (e *CacheEntry) Fresh(ttl time.Duration) bool {
+	return time.Since(e.FetchedAt) < ttl
+}
+
+// Cache is the on-disk cache of API responses
+type Cache struct {
+	dir     string
+	entries map[string]*CacheEntry
+}
+
+// Offline reports whether --offline is set
+func Offline() bool {
+	return viper.GetBool("offline")
+}
+
+// CacheTTL returns how long cached responses are served without revalidation
+func CacheTTL() time.Duration {
+	value := viper.GetString("cache.ttl")
+	if value == "" {
+		return defaultCacheTTL
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		Warn("Invalid cache.ttl, using default", map[string]interface{}{"value": value})
+		return defaultCacheTTL
+	}
+	return ttl
+}
+
+// CacheMaxSize returns the size cap of the cache in bytes
+func CacheMaxSize() int64 {
+	value := viper.GetString("cache.max_size")
+	if value == "" {
+		return defaultCacheMaxSize
+	}
+	size, err := ParseSize(value)
+	if err != nil {
+		Warn("Invalid cache.max_size, using default", map[string]interface{}{"value": value})
+		return defaultCacheMaxSize
+	}
+	return size
+}
+
+// ParseSize parses a size such as 512, 100KB or 50MB
+func ParseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return n * multiplier, nil
+}
+
+// FormatSize formats a size in bytes for humans
+func FormatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
+// OpenCache loads the cache index, creating the cache directory if needed
+func OpenCache() (*Cache, error) {
+	c := &Cache{dir: CacheDir(), entries: map[string]*CacheEntry{}}
+	if err := os.MkdirAll(filepath.Join(c.dir, "objects"), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %v", err)
+	}
+	// Private snippets are cached, so caches created readable by others are
+	// closed too
+	if err := os.Chmod(c.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to protect cache directory: %v", err)
+	}
+
+	data, err := os.ReadFile(c.indexPath())
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache index: %v", err)
+	}
+	var entries []*CacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		// A corrupt index only loses the cache, start over
+		Warn("Cache index is corrupt, starting with an empty cache", map[string]interface{}{"error": err.Error()})
+		return c, nil
+	}
+	for _, entry := range entries {
+		c.entries[entry.Key] = entry
+	}
+	return c, nil
+}
+
+func (c *Cache) indexPath() string {
+	return filepath.Join(c.dir, "index.json")
+}
+
+func (c *Cache) objectPath(hash string) string {
+	return filepath.Join(c.dir, "objects", hash)
+}
+
+// save writes the index atomically
+func (c *Cache) save() error {
+	data, err := json.MarshalIndent(c.Entries(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cache index: %v", err)
+	}
+	tmp := c.indexPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write cache index: %v", err)
+	}
+	return os.Rename(tmp, c.indexPath())
+}
+
+// Entries returns the cache entries, most recently used first
+func (c *Cache) Entries() []CacheEntry {
+	entries := make([]CacheEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].AccessedAt.Equal(entries[j].AccessedAt) {
+			return entries[i].Key < entries[j].Key
+		}
+		return entries[i].AccessedAt.After(entries[j].AccessedAt)
+	})
+	return entries
+}
+
+// Lookup returns the entry for key without reading its data
+func (c *Cache) Lookup(key string) *CacheEntry {
+	return c.entries[key]
+}
+
+// Peek returns the data for key without marking it as recently used
+func (c *Cache) Peek(key string) ([]byte, error) {
+	entry := c.entries[key]
+	if entry == nil {
+		return nil, nil
+	}
+	return os.ReadFile(c.objectPath(entry.Hash))
+}
+
+// Get returns the entry and data for key and marks it as recently used
+func (c *Cache) Get(key string) (*CacheEntry, []byte, error) {
+	entry := c.entries[key]
+	if entry == nil {
+		return nil, nil, nil
+	}
+	data, err := os.ReadFile(c.objectPath(entry.Hash))
+	if err != nil {
+		// The object is gone, forget the entry
+		delete(c.entries, key)
+		c.save()
+		return nil, nil, nil
+	}
+	entry.AccessedAt = time.Now()
+	return entry, data, c.save()
+}
+
+// Put stores data under key and evicts the least recently used entries when
+// the cache grows past its size cap
+func (c *Cache) Put(key, label string, data []byte, etag string) error {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	path := c.objectPath(hash)
+	if !FileExists(path) {
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return fmt.Errorf("failed to write cache object: %v", err)
+		}
+	}
+
+	old := c.entries[key]
+	now := time.Now()
+	c.entries[key] = &CacheEntry{
+		Key:        key,
+		Label:      label,
+		Hash:       hash,
+		Size:       int64(len(data)),
+		ETag:       etag,
+		FetchedAt:  now,
+		AccessedAt: now,
+	}
+	if old != nil && old.Hash != hash {
+		c.removeObject(old.Hash)
+	}
+
+	c.evict(CacheMaxSize())
+	return c.save()
+}
+
+// Touch marks a revalidated entry as fresh again
+func (c *Cache) Touch(key string) error {
+	entry := c.entries[key]
+	if entry == nil {
+		return nil
+	}
+	entry.FetchedAt = time.Now()
+	entry.AccessedAt = entry.FetchedAt
+	return c.save()
+}
+
+// Remove deletes the entry for key
+func (c *Cache) Remove(key string) error {
+	entry := c.entries[key]
+	if entry == nil {
+		return nil
+	}
+	delete(c.entries, key)
+	c.removeObject(entry.Hash)
+	return c.save()
+}
+
+// removeObject deletes an object unless another entry still uses it
+func (c *Cache) removeObject(hash string) {
+	for _, entry := range c.entries {
+		if entry.Hash == hash {
+			return
+		}
+	}
+	os.Remove(c.objectPath(hash))
+}
+
+// Size returns the total size of the cached objects
+func (c *Cache) Size() int64 {
+	seen := map[string]bool{}
+	var size int64
+	for _, entry := range c.entries {
+		if !seen[entry.Hash] {
+			seen[entry.Hash] = true
+			size += entry.Size
+		}
+	}
+	return size
+}
+
+// evict drops least recently used entries until the cache fits in maxSize
+func (c *Cache) evict(maxSize int64) {
+	if maxSize <= 0 {
+		return
+	}
+	entries := c.Entries()
+	for i := len(entries) - 1; i >= 0 && c.Size() > maxSize; i-- {
+		key := entries[i].Key
+		hash := c.entries[key].Hash
+		delete(c.entries, key)
+		c.removeObject(hash)
+		Debug("Evicted cache entry", map[string]interface{}{"key": key})
+	}
+}
+
+// Prune removes entries older than maxAge and objects no entry refers to. It
+// returns the number of removed entries.
+func (c *Cache) Prune(maxAge time.Duration) (int, error) {
+	removed := 0
+	for key, entry := range c.entries {
+		if !entry.Fresh(maxAge) {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+
+	// Remove orphaned objects
+	used := map[string]bool{}
+	for _, entry := range c.entries {
+		used[entry.Hash] = true
+	}
+	objects, err := os.ReadDir(filepath.Join(c.dir, "objects"))
+	if err != nil {
+		return removed, fmt.Errorf("failed to read cache objects: %v", err)
+	}
+	for _, object := range objects {
+		if !used[object.Name()] {
+			os.Remove(c.objectPath(object.Name()))
+		}
+	}
+	return removed, c.save()
+}
+
+// Clear removes everything from the cache
+func (c *Cache) Clear() error {
+	c.entries = map[string]*CacheEntry{}
+	if err := os.RemoveAll(filepath.Join(c.dir, "objects")); err != nil {
+		return fmt.Errorf("failed to clear cache: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(c.dir, "objects"), 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %v", err)
+	}
+	return c.save()
+}
+
+// cacheScope returns the suffix that keeps the cache entries of profiles
+// apart, e.g. "@work:snippets.example.com". Profiles may be different
+// accounts on the same API, so their private snippets are never shared.
+func cacheScope() string {
+	host := BaseURL()
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		host = u.Host + strings.TrimRight(u.Path, "/")
+	}
+	return "@" + ActiveProfileName() + ":" + host
+}
+
+// snippetCacheKey returns the cache key of a snippet fetched from the active
+// profile's API
+func snippetCacheKey(snippetID string) string {
+	return "snippet:" + snippetID + cacheScope()
+}
+
+// cachedGet serves an API GET request through the cache. Fresh entries are
+// returned without touching the network, stale ones are revalidated with their
+// ETag, and decode decides whether a response is worth caching and returns its
+// label for `cache ls`.
+func cachedGet(key, apiURL, apiKey string, decode func([]byte) (string, error)) ([]byte, error) {
+	cache, err := OpenCache()
+	if err != nil {
+		Warn("Cache unavailable", map[string]interface{}{"error": err.Error()})
+		if Offline() {
+			return nil, fmt.Errorf("%w: %v", ErrOffline, err)
+		}
+		resp, err := apiGet(apiURL, apiKey, "")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := decode(resp.Body); err != nil {
+			return nil, err
+		}
+		return resp.Body, nil
+	}
+
+	entry, cached, err := cache.Get(key)
+	if err != nil {
+		Warn("Failed to update cache index", map[string]interface{}{"error": err.Error()})
+	}
+
+	if Offline() {
+		if entry == nil {
+			return nil, fmt.Errorf("%w: %s is not cached", ErrOffline, key)
+		}
+		return cached, nil
+	}
+	if entry != nil && entry.Fresh(CacheTTL()) {
+		Debug("Serving from cache", map[string]interface{}{"key": key})
+		return cached, nil
+	}
+
+	etag := ""
+	if entry != nil {
+		etag = entry.ETag
+	}
+	resp, err := apiGet(apiURL, apiKey, etag)
+	if err != nil {
+		var netErr *NetworkError
+		if entry != nil && errors.As(err, &netErr) {
+			Warn("API unreachable, serving stale cache entry", map[string]interface{}{"key": key})
+			return cached, nil
+		}
+		return nil, err
+	}
+	if resp.NotModified && entry != nil {
+		Debug("Cache entry revalidated", map[string]interface{}{"key": key})
+		if err := cache.Touch(key); err != nil {
+			Warn("Failed to update cache index", map[string]interface{}{"error": err.Error()})
+		}
+		return cached, nil
+	}
+
+	label, err := decode(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.Put(key, label, resp.Body, resp.ETag); err != nil {
+		Warn("Failed to cache API response", map[string]interface{}{"error": err.Error()})
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCacheIsScopedByProfile(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	loadTestConfig(t, `
+profiles:
+  work:
+    base_url: https://snippets.example.com
+  personal:
+    base_url: https://snippets.example.com
+`, "")
+	t.Cleanup(func() { SetProfileOverride("") })
+
+	cache, err := OpenCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetProfileOverride("work")
+	workKey := snippetCacheKey("abc")
+	body := `{"success":true,"data":{"shortId":"abc","title":"Work secret","code":"x := 1"}}`
+	if err := cache.Put(workKey, "abc", []byte(body), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	SetProfileOverride("personal")
+	if key := snippetCacheKey("abc"); key == workKey {
+		t.Fatalf("profiles on the same API share the cache key %q", key)
+	}
+	if _, err := os.Stat(searchIndexPath()); err == nil {
+		t.Fatal("personal profile has an index before searching")
+	}
+	idx, err := OpenSearchIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Docs) != 0 {
+		t.Errorf("personal profile's index has %d docs from another profile", len(idx.Docs))
+	}
+
+	SetProfileOverride("work")
+	idx, err = OpenSearchIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Docs) != 1 {
+		t.Errorf("work profile's index has %d docs, want 1", len(idx.Docs))
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	for _, path := range []string{cache.indexPath(), cache.objectPath(cache.Lookup(workKey).Hash), searchIndexPath()} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("%s mode = %v, want 0600", filepath.Base(path), info.Mode().Perm())
+		}
+	}
+}
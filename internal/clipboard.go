@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+)
+
+// Clipboard methods reported by CopyToClipboard
+const (
+	ClipboardLocal = "clipboard"
+	ClipboardOSC52 = "osc52"
+)
+
+// OSC52Limit is the payload size above which many terminals truncate or
+// ignore OSC 52 sequences
+const OSC52Limit = 74 << 10 // About 100KB once base64 encoded
+
+// ErrNoClipboard is returned when neither a local clipboard nor a terminal
+// for OSC 52 is available
+var ErrNoClipboard = errors.New("no clipboard available: install xclip, xsel or wl-clipboard, or run in a terminal that supports OSC 52")
+
+// RemoteSession reports whether we are running over SSH, where the local
+// clipboard belongs to the remote machine
+func RemoteSession() bool {
+	return os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != ""
+}
+
+// CopyToClipboard puts text on the clipboard and returns the method used. The
+// system clipboard is used when available; over SSH, or when forceOSC52 is
+// set or the system clipboard fails, an OSC 52 escape sequence asks the
+// terminal to set its clipboard instead, wrapped for tmux and screen.
+func CopyToClipboard(text string, forceOSC52 bool) (string, error) {
+	if !forceOSC52 && !RemoteSession() && !clipboard.Unsupported {
+		err := clipboard.WriteAll(text)
+		if err == nil {
+			return ClipboardLocal, nil
+		}
+		Debug("System clipboard failed, trying OSC 52", map[string]interface{}{"error": err.Error()})
+	}
+
+	// The sequence has to reach the terminal, which stdout may not be
+	out := os.Stderr
+	if !IsTerminal(out) {
+		out = os.Stdout
+	}
+	if !IsTerminal(out) {
+		return "", ErrNoClipboard
+	}
+
+	seq := osc52.New(text)
+	switch {
+	case os.Getenv("TMUX") != "":
+		seq = seq.Tmux()
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		seq = seq.Screen()
+	}
+	if _, err := seq.WriteTo(out); err != nil {
+		return "", err
+	}
+	return ClipboardOSC52, nil
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"io"
+	"os"
+	"sort"
+
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/colorprofile"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/spf13/viper"
+)
+
+// DefaultTheme is the chroma style used when neither theme nor the UI theme
+// choose one
+const DefaultTheme = "onedark"
+
+// Stdout and Stderr write styled output to the standard streams, reduced to
+// the colors each of them supports. They are set up by ApplyColorProfile.
+var (
+	Stdout io.Writer = os.Stdout
+	Stderr io.Writer = os.Stderr
+)
+
+// ColorProfile detects the colors stdout supports
+func ColorProfile() colorprofile.Profile {
+	return StreamColorProfile(os.Stdout)
+}
+
+// StreamColorProfile detects the colors a stream supports. NO_COLOR,
+// CLICOLOR, CLICOLOR_FORCE and TERM are honored, and --no-color (or no_color
+// in the config) turns colors off.
+func StreamColorProfile(f *os.File) colorprofile.Profile {
+	if viper.GetBool("no_color") {
+		return colorprofile.Ascii
+	}
+	return colorprofile.Detect(f, os.Environ())
+}
+
+// ApplyColorProfile detects the profiles of stdout and stderr. Styles render
+// with the richer one, so status on stderr keeps its colors when stdout is
+// piped, and Stdout and Stderr reduce them to what their stream supports.
+func ApplyColorProfile() {
+	stdout, stderr := StreamColorProfile(os.Stdout), StreamColorProfile(os.Stderr)
+	Stdout = &colorprofile.Writer{Forward: os.Stdout, Profile: stdout}
+	Stderr = &colorprofile.Writer{Forward: os.Stderr, Profile: stderr}
+	switch max(stdout, stderr) {
+	case colorprofile.TrueColor:
+		lipgloss.SetColorProfile(termenv.TrueColor)
+	case colorprofile.ANSI256:
+		lipgloss.SetColorProfile(termenv.ANSI256)
+	case colorprofile.ANSI:
+		lipgloss.SetColorProfile(termenv.ANSI)
+	default:
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+}
+
+// ChromaFormatter returns the chroma terminal formatter for the detected
+// profile, or "noop" when colors are off
+func ChromaFormatter() string {
+	switch ColorProfile() {
+	case colorprofile.TrueColor:
+		return "terminal16m"
+	case colorprofile.ANSI256:
+		return "terminal256"
+	case colorprofile.ANSI:
+		return "terminal16"
+	}
+	return "noop"
+}
+
+// Theme returns the chroma style used for syntax highlighting: the theme
+// setting, or the style suggested by the UI theme
+func Theme() string {
+	fallback := DefaultTheme
+	if _, ok := styles.Registry[activeUITheme.Highlight]; ok {
+		fallback = activeUITheme.Highlight
+	}
+	theme := viper.GetString("theme")
+	if theme == "" {
+		return fallback
+	}
+	if _, ok := styles.Registry[theme]; !ok {
+		Warn("Unknown theme, using default", map[string]interface{}{"theme": theme})
+		return fallback
+	}
+	return theme
+}
+
+// Themes returns the names of the available highlighting themes
+func Themes() []string {
+	names := styles.Names()
+	sort.Strings(names)
+	return names
+}
@@ -1,53 +1,251 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
+// EnvPrefix prefixes the environment variables of settings, e.g.
+// SNIPPETKIT_LOG_LEVEL for log_level and SNIPPETKIT_CACHE_TTL for cache.ttl
+const EnvPrefix = "SNIPPETKIT"
+
+// projectConfigName is the config file looked up from the current directory
+// upwards, e.g. in a repository
+const projectConfigName = ".snippetkit.yaml"
+
+// Config files read by LoadConfig
+var (
+	userConfigPath    string
+	projectConfigPath string
+	// skippedProjectKeys are the settings left out of the project config
+	skippedProjectKeys []string
+)
+
+// boundFlags maps settings to the flags bound to them
+var boundFlags = map[string]*pflag.Flag{}
+
+// ConfigureEnv makes settings readable from SNIPPETKIT_ environment variables.
+// It runs before flags are parsed so every setting, including the flag bound
+// ones, honors them.
+func ConfigureEnv() {
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	viper.AutomaticEnv()
+}
+
+// BindFlag binds a setting to a flag. A flag given on the command line takes
+// precedence over the environment and the config files.
+func BindFlag(key string, flag *pflag.Flag) {
+	viper.BindPFlag(key, flag)
+	boundFlags[key] = flag
+}
+
+// EnvVar returns the environment variable of a setting
+func EnvVar(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// LoadConfig reads the user config file, or the one given with --config, and
+// merges the project settings of the project config file over it, e.g.
+// search.limit but not base_url. Settings are resolved in the order flag,
+// environment, project config, user config, default.
 func LoadConfig() {
-	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
-	viper.AddConfigPath(filepath.Join(os.Getenv("HOME"), ".config/snippetkit"))
-	viper.AddConfigPath(".")
-	viper.AutomaticEnv()
+	userConfigPath = GetConfigPath()
+	projectConfigPath = ""
+	// An explicit config file is used on its own
+	if viper.GetString("config") == "" {
+		projectConfigPath = findProjectConfig()
+	}
+	if err := readConfigFiles(); err != nil {
+		Warn("Failed to read config file. Using default settings.", map[string]interface{}{"error": err.Error()})
+	}
+}
 
-	if err := viper.ReadInConfig(); err != nil {
-		Warn("No config file found. Using default settings.", nil)
+// readConfigFiles (re)reads the config files. Settings are written to the user
+// config file, so it is left as viper's config file.
+func readConfigFiles() error {
+	viper.SetConfigFile(userConfigPath)
+	var readErr error
+	if err := viper.ReadInConfig(); err != nil && FileExists(userConfigPath) {
+		readErr = err
+	}
+	skippedProjectKeys = nil
+	if projectConfigPath != "" {
+		config, err := readConfigFile(projectConfigPath)
+		if err != nil {
+			return err
+		}
+		var allowed map[string]interface{}
+		allowed, skippedProjectKeys = filterProjectConfig("", config)
+		if err := viper.MergeConfigMap(allowed); err != nil {
+			readErr = err
+		}
 	}
+	return readErr
 }
 
+// filterProjectConfig keeps the project settings of a project config and
+// returns the keys it left out
+func filterProjectConfig(prefix string, config map[string]interface{}) (map[string]interface{}, []string) {
+	allowed := map[string]interface{}{}
+	var skipped []string
+	for name, value := range config {
+		key := strings.ToLower(name)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			nestedAllowed, nestedSkipped := filterProjectConfig(key, nested)
+			if len(nestedAllowed) > 0 {
+				allowed[name] = nestedAllowed
+			}
+			skipped = append(skipped, nestedSkipped...)
+			continue
+		}
+		if ProjectSetting(key) {
+			allowed[name] = value
+		} else {
+			skipped = append(skipped, key)
+		}
+	}
+	sort.Strings(skipped)
+	return allowed, skipped
+}
+
+// SkippedProjectSettings returns the keys of the project config that were
+// ignored because only the user config may set them
+func SkippedProjectSettings() []string {
+	return skippedProjectKeys
+}
+
+// findProjectConfig looks for .snippetkit.yaml in the current directory and
+// its parents
+func findProjectConfig() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, projectConfigName)
+		// The user config is never a project config, e.g. when run from $HOME
+		if FileExists(path) && path != userConfigPath {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ProjectConfigFile returns the project config file in use, if any
+func ProjectConfigFile() string {
+	return projectConfigPath
+}
+
+// GetAPIKey returns the verified API token of the active profile
 func GetAPIKey() (string, error) {
-	apiToken := viper.GetString("api_key")
-	if valid, err := VerifyToken(apiToken); !valid || err != nil {
-		return "", fmt.Errorf("API token is invalid or expired. Please run 'snippetkit login' to authenticate")
+	profile, err := ActiveProfile()
+	if err != nil {
+		return "", err
+	}
+	apiToken, _, err := LoadAPIKey(profile)
+	if err != nil {
+		return "", err
+	}
+	// The token can't be verified offline, cached data is served regardless
+	if Offline() {
+		return apiToken, nil
 	}
 	if apiToken == "" {
-		return "", fmt.Errorf("API token is missing. Please run 'snippetkit login' to authenticate")
+		return "", fmt.Errorf("%w: API token is missing for profile %s. Please run 'snippetkit login' to authenticate", ErrUnauthorized, profile.Name)
 	}
-	return viper.GetString("api_key"), nil
+	if valid, err := VerifyToken(apiToken); !valid || err != nil {
+		var netErr *NetworkError
+		if errors.As(err, &netErr) {
+			return "", err
+		}
+		return "", fmt.Errorf("%w: API token is invalid or expired. Please run 'snippetkit login' to authenticate", ErrUnauthorized)
+	}
+	return apiToken, nil
 }
 
-func SetAPIKey(apiKey string) (bool, error) {
-	configPath := filepath.Join(os.Getenv("HOME"), ".config/snippetkit/config.yaml")
+// CheckAPIKey verifies apiKey against the active profile's API
+func CheckAPIKey(apiKey string) error {
 	if valid, err := VerifyToken(apiKey); !valid || err != nil {
 		Error("API token is invalid or expired. Please run 'snippetkit login' to authenticate", err, nil)
-		return false, fmt.Errorf("API token is invalid or expired")
+		var netErr *NetworkError
+		if errors.As(err, &netErr) {
+			return err
+		}
+		return fmt.Errorf("%w: API token is invalid or expired", ErrUnauthorized)
 	}
-	viper.Set("api_key", apiKey)
-	viper.WriteConfigAs(configPath)
-	return true, nil
+	return nil
 }
 
-func RemoveAPIKey() error {
-	configPath := filepath.Join(os.Getenv("HOME"), ".config/snippetkit/config.yaml")
-	viper.Set("api_key", "")
-	return viper.WriteConfigAs(configPath)
+// SetAPIKey stores apiKey for the active profile with its credential helper,
+// or helper when given, which then becomes the profile's helper. It returns
+// the name of the store used. Check the key with CheckAPIKey first.
+func SetAPIKey(apiKey, helper string) (string, error) {
+	profile, err := ActiveProfile()
+	if err != nil {
+		return "", err
+	}
+	if helper == "" {
+		helper = profile.CredentialHelperName()
+	}
+	store, err := NewCredentialStore(helper)
+	if err != nil {
+		return "", err
+	}
+	if err := store.Store(profile, apiKey); err != nil {
+		return "", fmt.Errorf("failed to store API token with %s: %w", store.Name(), err)
+	}
+	if previous := profile.CredentialHelperName(); helper != previous {
+		if err := setProfileHelper(profile.Name, helper); err != nil {
+			return "", err
+		}
+		// Don't leave the token behind in the store it moved from
+		if old, err := NewCredentialStore(previous); err == nil && old.Name() != CredentialPlaintext {
+			if err := old.Erase(profile); err != nil {
+				Warn("Failed to erase API token from the previous credential store", map[string]interface{}{"store": previous, "error": err.Error()})
+			}
+		}
+	}
+	// Don't leave a plaintext copy behind once another store holds the token
+	if store.Name() != CredentialPlaintext && profile.APIKey != "" {
+		if err := setProfileKey(profile.Name, ""); err != nil {
+			return "", err
+		}
+	}
+	return store.Name(), nil
 }
 
-func GetConfigPath() string {
-	return filepath.Join(os.Getenv("HOME"), ".config/snippetkit/config.yaml")
+// RemoveAPIKey removes the API token of the active profile from its
+// credential store and the config file
+func RemoveAPIKey() error {
+	profile, err := ActiveProfile()
+	if err != nil {
+		return err
+	}
+	store, err := CredentialStoreFor(profile)
+	if err != nil {
+		return err
+	}
+	if err := store.Erase(profile); err != nil {
+		return fmt.Errorf("failed to erase API token from %s: %w", store.Name(), err)
+	}
+	if store.Name() != CredentialPlaintext && profile.APIKey != "" {
+		return setProfileKey(profile.Name, "")
+	}
+	return nil
 }
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// loadTestConfig points the config at a user and a project config file with
+// the given contents
+func loadTestConfig(t *testing.T, user, project string) {
+	t.Helper()
+	dir := t.TempDir()
+	viper.Reset()
+	ConfigureEnv()
+	userConfigPath = filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(userConfigPath, []byte(user), 0600); err != nil {
+		t.Fatal(err)
+	}
+	projectConfigPath = ""
+	if project != "" {
+		projectConfigPath = filepath.Join(dir, projectConfigName)
+		if err := os.WriteFile(projectConfigPath, []byte(project), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := readConfigFiles(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		viper.Reset()
+		userConfigPath, projectConfigPath, skippedProjectKeys = "", "", nil
+		boundFlags = map[string]*pflag.Flag{}
+	})
+}
+
+func TestProjectConfigOnlySetsProjectSettings(t *testing.T) {
+	loadTestConfig(t, "base_url: https://user.example.com\n", `
+base_url: http://127.0.0.1:18765
+credential_helper: /bin/sh
+current_profile: other
+theme: monokai
+profiles:
+  default:
+    api_key: stolen-token
+search:
+  limit: 3
+`)
+
+	if got := viper.GetString("base_url"); got != "https://user.example.com" {
+		t.Errorf("base_url = %q, want the user config's", got)
+	}
+	for _, key := range []string{"credential_helper", "current_profile", "profiles.default.api_key"} {
+		if viper.IsSet(key) {
+			t.Errorf("%s was taken from the project config", key)
+		}
+	}
+	if got := viper.GetInt("search.limit"); got != 3 {
+		t.Errorf("search.limit = %d, want 3", got)
+	}
+	if got := viper.GetString("theme"); got != "monokai" {
+		t.Errorf("theme = %q, want monokai", got)
+	}
+
+	want := []string{"base_url", "credential_helper", "current_profile", "profiles.default.api_key"}
+	if got := SkippedProjectSettings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SkippedProjectSettings() = %v, want %v", got, want)
+	}
+}
+
+func TestSettingOriginPrecedence(t *testing.T) {
+	loadTestConfig(t, "search:\n  limit: 5\nlog_level: warn\nbase_url: https://user.example.com\n",
+		"search:\n  limit: 7\nbase_url: https://project.example.com\n")
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("log-level", "", "")
+	BindFlag("log_level", flags.Lookup("log-level"))
+
+	tests := []struct {
+		key    string
+		origin string
+	}{
+		{"search.limit", OriginProject},
+		{"base_url", OriginUser}, // Ignored in the project config
+		{"log_level", OriginUser},
+		{"cache.ttl", OriginDefault},
+	}
+	for _, tt := range tests {
+		if origin, _ := SettingOrigin(tt.key); origin != tt.origin {
+			t.Errorf("SettingOrigin(%q) = %s, want %s", tt.key, origin, tt.origin)
+		}
+	}
+
+	t.Setenv("SNIPPETKIT_LOG_LEVEL", "debug")
+	if origin, source := SettingOrigin("log_level"); origin != OriginEnv || source != "SNIPPETKIT_LOG_LEVEL" {
+		t.Errorf("SettingOrigin(log_level) = %s %s, want env", origin, source)
+	}
+	if err := flags.Set("log-level", "error"); err != nil {
+		t.Fatal(err)
+	}
+	if origin, source := SettingOrigin("log_level"); origin != OriginFlag || source != "--log-level" {
+		t.Errorf("SettingOrigin(log_level) = %s %s, want flag", origin, source)
+	}
+	if got := viper.GetString("log_level"); got != "error" {
+		t.Errorf("log_level = %q, want the flag's value", got)
+	}
+}
@@ -0,0 +1,435 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Built-in credential stores. Any other name is an external helper.
+const (
+	CredentialPlaintext = "plaintext" // api_key in the config file
+	CredentialFile      = "file"      // Encrypted credentials file
+)
+
+// Token sources reported by LoadAPIKey
+const (
+	TokenSourceEnv     = "env"
+	TokenSourceProfile = "profile"
+)
+
+// credentialHelperPrefix names external helpers: the helper "pass" is the
+// program snippetkit-credential-pass on the PATH
+const credentialHelperPrefix = "snippetkit-credential-"
+
+// ErrBadPassphrase is returned when the credentials file can't be decrypted
+var ErrBadPassphrase = errors.New("wrong passphrase for the credentials file")
+
+// CredentialStore keeps the API tokens of profiles
+type CredentialStore interface {
+	// Name identifies the store, e.g. plaintext, file or a helper name
+	Name() string
+	// Get returns the token of a profile, or "" when none is stored
+	Get(profile *Profile) (string, error)
+	Store(profile *Profile, token string) error
+	Erase(profile *Profile) error
+}
+
+// credentialHelperPattern restricts helper names, so a config file can only
+// pick a snippetkit-credential-<name> program from the PATH and never a path
+var credentialHelperPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// ValidateCredentialHelper checks a credential helper name without looking
+// the helper up
+func ValidateCredentialHelper(name string) error {
+	if name == CredentialPlaintext || name == CredentialFile || credentialHelperPattern.MatchString(name) {
+		return nil
+	}
+	return fmt.Errorf("invalid credential helper %q: use plaintext, file or the <name> of a %s<name> program on the PATH", name, credentialHelperPrefix)
+}
+
+// NewCredentialStore returns the store for a helper name: plaintext, file or
+// the name of a snippetkit-credential-<name> program on the PATH
+func NewCredentialStore(name string) (CredentialStore, error) {
+	switch name {
+	case "", CredentialPlaintext:
+		return plaintextStore{}, nil
+	case CredentialFile:
+		return &fileStore{path: CredentialsFile()}, nil
+	}
+	if err := ValidateCredentialHelper(name); err != nil {
+		return nil, err
+	}
+	program := credentialHelperPrefix + name
+	path, err := exec.LookPath(program)
+	if err != nil {
+		return nil, fmt.Errorf("credential helper %s not found: %v", program, err)
+	}
+	return helperStore{name: name, path: path}, nil
+}
+
+// CredentialStoreFor returns the store of a profile: its credential_helper,
+// or the credential_helper setting
+func CredentialStoreFor(profile *Profile) (CredentialStore, error) {
+	return NewCredentialStore(profile.CredentialHelperName())
+}
+
+// CredentialHelperName returns the credential helper of a profile
+func (p *Profile) CredentialHelperName() string {
+	if p.CredentialHelper != "" {
+		return p.CredentialHelper
+	}
+	if helper := viper.GetString("credential_helper"); helper != "" {
+		return helper
+	}
+	return CredentialPlaintext
+}
+
+// LoadAPIKey returns the token of a profile and where it came from: env for
+// SNIPPETKIT_API_KEY, profile for the config file, or the credential store
+func LoadAPIKey(profile *Profile) (token, source string, err error) {
+	if token := os.Getenv(EnvVar("api_key")); token != "" {
+		return token, TokenSourceEnv, nil
+	}
+	store, err := CredentialStoreFor(profile)
+	if err != nil {
+		return "", "", err
+	}
+	token, err = store.Get(profile)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read credentials from %s: %w", store.Name(), err)
+	}
+	source = store.Name()
+	if source == CredentialPlaintext {
+		source = TokenSourceProfile
+	}
+	return token, source, nil
+}
+
+// TokenSourceDescription describes a token source reported by LoadAPIKey
+func TokenSourceDescription(source string) string {
+	switch source {
+	case TokenSourceEnv:
+		return EnvVar("api_key")
+	case TokenSourceProfile:
+		return "the config file"
+	case CredentialFile:
+		return "the encrypted credentials file"
+	}
+	return "credential helper " + source
+}
+
+// credentialKey identifies a profile's token in the file store and to helpers
+func credentialKey(profile *Profile) string {
+	return profile.Name + "@" + credentialHost(profile)
+}
+
+func credentialHost(profile *Profile) string {
+	if u, err := url.Parse(profile.BaseURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return profile.BaseURL
+}
+
+// plaintextStore keeps tokens in the config file, which is only readable by
+// the user
+type plaintextStore struct{}
+
+func (plaintextStore) Name() string { return CredentialPlaintext }
+
+func (plaintextStore) Get(profile *Profile) (string, error) {
+	return profile.APIKey, nil
+}
+
+func (plaintextStore) Store(profile *Profile, token string) error {
+	return setProfileKey(profile.Name, token)
+}
+
+func (plaintextStore) Erase(profile *Profile) error {
+	return setProfileKey(profile.Name, "")
+}
+
+// helperStore runs an external credential helper. Like git credential
+// helpers, it is called with get, store or erase and reads key=value lines on
+// stdin:
+//
+//	protocol=https
+//	host=snippetkit.vercel.app
+//	profile=default
+//	token=...          (store only)
+//
+// get prints token=<token> on stdout, or nothing when it has no token.
+type helperStore struct {
+	name string
+	path string
+}
+
+func (h helperStore) Name() string { return h.name }
+
+func (h helperStore) Get(profile *Profile) (string, error) {
+	out, err := h.run("get", profile, "")
+	if err != nil {
+		return "", err
+	}
+	values := parseCredentialLines(out)
+	if token := values["token"]; token != "" {
+		return token, nil
+	}
+	// git helpers answer with password
+	return values["password"], nil
+}
+
+func (h helperStore) Store(profile *Profile, token string) error {
+	_, err := h.run("store", profile, token)
+	return err
+}
+
+func (h helperStore) Erase(profile *Profile) error {
+	_, err := h.run("erase", profile, "")
+	return err
+}
+
+func (h helperStore) run(action string, profile *Profile, token string) ([]byte, error) {
+	var input bytes.Buffer
+	protocol := "https"
+	if u, err := url.Parse(profile.BaseURL); err == nil && u.Scheme != "" {
+		protocol = u.Scheme
+	}
+	fmt.Fprintf(&input, "protocol=%s\nhost=%s\nprofile=%s\n", protocol, credentialHost(profile), profile.Name)
+	if token != "" {
+		fmt.Fprintf(&input, "token=%s\n", token)
+	}
+	input.WriteString("\n")
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(h.path, action)
+	cmd.Stdin = &input
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("credential helper %s %s failed: %s", h.name, action, msg)
+	}
+	return out, nil
+}
+
+// parseCredentialLines parses key=value lines up to the first blank line
+func parseCredentialLines(data []byte) map[string]string {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			break
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// CredentialsFile returns the location of the encrypted credentials file
+func CredentialsFile() string {
+	return filepath.Join(ConfigDir(), "credentials.enc")
+}
+
+// Encryption parameters of the credentials file
+const (
+	credentialsVersion    = 1
+	credentialsIterations = 600000
+	credentialsKeySize    = 32 // AES-256
+)
+
+// encryptedCredentials is the on-disk format of the credentials file. The
+// tokens are a JSON object encrypted with AES-GCM under a key derived from
+// the passphrase with PBKDF2-SHA256.
+type encryptedCredentials struct {
+	Version    int    `json:"version"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// fileStore keeps tokens in a file encrypted with a passphrase, read from
+// SNIPPETKIT_PASSPHRASE or asked for
+type fileStore struct {
+	path       string
+	passphrase string
+}
+
+func (f *fileStore) Name() string { return CredentialFile }
+
+func (f *fileStore) Get(profile *Profile) (string, error) {
+	if !FileExists(f.path) {
+		return "", nil
+	}
+	tokens, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	return tokens[credentialKey(profile)], nil
+}
+
+func (f *fileStore) Store(profile *Profile, token string) error {
+	tokens := map[string]string{}
+	if FileExists(f.path) {
+		var err error
+		if tokens, err = f.load(); err != nil {
+			return err
+		}
+	} else if err := f.newPassphrase(); err != nil {
+		return err
+	}
+	tokens[credentialKey(profile)] = token
+	return f.save(tokens)
+}
+
+func (f *fileStore) Erase(profile *Profile) error {
+	if !FileExists(f.path) {
+		return nil
+	}
+	tokens, err := f.load()
+	if err != nil {
+		return err
+	}
+	delete(tokens, credentialKey(profile))
+	return f.save(tokens)
+}
+
+// unlock asks for the passphrase once
+func (f *fileStore) unlock() error {
+	if f.passphrase != "" {
+		return nil
+	}
+	if passphrase := os.Getenv(EnvVar("passphrase")); passphrase != "" {
+		f.passphrase = passphrase
+		return nil
+	}
+	passphrase, err := PasswordPrompt("Passphrase for " + f.path)
+	if err != nil {
+		return fmt.Errorf("%w: set %s to unlock the credentials file", err, EnvVar("passphrase"))
+	}
+	f.passphrase = passphrase
+	return nil
+}
+
+// newPassphrase chooses the passphrase of a new credentials file
+func (f *fileStore) newPassphrase() error {
+	if passphrase := os.Getenv(EnvVar("passphrase")); passphrase != "" {
+		f.passphrase = passphrase
+		return nil
+	}
+	passphrase, err := PasswordPrompt("New passphrase for the credentials file")
+	if err != nil {
+		return fmt.Errorf("%w: set %s to create the credentials file", err, EnvVar("passphrase"))
+	}
+	confirm, err := PasswordPrompt("Repeat the passphrase")
+	if err != nil {
+		return err
+	}
+	if passphrase == "" || passphrase != confirm {
+		return errors.New("the passphrases are empty or don't match")
+	}
+	f.passphrase = passphrase
+	return nil
+}
+
+func (f *fileStore) load() (map[string]string, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %v", err)
+	}
+	var file encryptedCredentials
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file %s: %v", f.path, err)
+	}
+	if file.Version != credentialsVersion {
+		return nil, fmt.Errorf("unsupported credentials file version %d", file.Version)
+	}
+	if err := f.unlock(); err != nil {
+		return nil, err
+	}
+
+	gcm, err := credentialsCipher(f.passphrase, file.Salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	tokens := map[string]string{}
+	if err := json.Unmarshal(plain, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %v", err)
+	}
+	return tokens, nil
+}
+
+func (f *fileStore) save(tokens map[string]string) error {
+	plain, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	file := encryptedCredentials{
+		Version:    credentialsVersion,
+		Iterations: credentialsIterations,
+		Salt:       make([]byte, 16),
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return err
+	}
+	gcm, err := credentialsCipher(f.passphrase, file.Salt, file.Iterations)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	file.Data = gcm.Seal(nil, file.Nonce, plain, nil)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := EnsureDirExists(f.path); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+	if err := os.WriteFile(f.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write credentials file: %v", err)
+	}
+	return os.Chmod(f.path, 0600)
+}
+
+// credentialsCipher derives the AES-GCM cipher of a passphrase
+func credentialsCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, credentialsKeySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
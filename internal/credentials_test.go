@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	t.Setenv("SNIPPETKIT_PASSPHRASE", "correct horse")
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	work := &Profile{Name: "work", BaseURL: "https://snippets.example.com"}
+	home := &Profile{Name: "home", BaseURL: DefaultBaseURL}
+
+	store := &fileStore{path: path}
+	if err := store.Store(work, "work-token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Store(home, "home-token"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("credentials file mode = %v, want 0600", info.Mode().Perm())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("work-token")) {
+		t.Error("credentials file holds the token in plaintext")
+	}
+
+	// A new store has to decrypt the file again
+	reopened := &fileStore{path: path}
+	for profile, want := range map[*Profile]string{work: "work-token", home: "home-token"} {
+		got, err := reopened.Get(profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Get(%s) = %q, want %q", profile.Name, got, want)
+		}
+	}
+
+	if err := reopened.Erase(work); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := (&fileStore{path: path}).Get(work); got != "" {
+		t.Errorf("Get(work) after Erase = %q, want empty", got)
+	}
+
+	t.Setenv("SNIPPETKIT_PASSPHRASE", "wrong")
+	if _, err := (&fileStore{path: path}).Get(home); !errors.Is(err, ErrBadPassphrase) {
+		t.Errorf("Get with a wrong passphrase = %v, want ErrBadPassphrase", err)
+	}
+}
+
+func TestNewCredentialStoreRejectsPaths(t *testing.T) {
+	for _, name := range []string{"/bin/sh", "./get", "../helper", `C:\helper.exe`, "sub/helper", ".hidden"} {
+		if _, err := NewCredentialStore(name); err == nil {
+			t.Errorf("NewCredentialStore(%q) accepted a path", name)
+		}
+	}
+}
+
+func TestHelperStoreGet(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("helper script needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\ncat >/dev/null\n[ \"$1\" = get ] && echo token=helper-token\nexit 0\n"
+	if err := os.WriteFile(filepath.Join(dir, credentialHelperPrefix+"test"), []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	store, err := NewCredentialStore("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Get(&Profile{Name: DefaultProfile, BaseURL: DefaultBaseURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "helper-token" {
+		t.Errorf("Get() = %q, want helper-token", got)
+	}
+}
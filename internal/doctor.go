@@ -0,0 +1,352 @@
+package internal
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Results of a doctor check
+const (
+	CheckPass = "pass"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// Latency above which the API is reported as slow
+const slowAPILatency = 2 * time.Second
+
+// Check is the result of one doctor check. Hint says how to fix a warning or
+// failure.
+type Check struct {
+	Name    string   `json:"name" yaml:"name"`
+	Status  string   `json:"status" yaml:"status"`
+	Message string   `json:"message" yaml:"message"`
+	Details []string `json:"details,omitempty" yaml:"details,omitempty"`
+	Hint    string   `json:"hint,omitempty" yaml:"hint,omitempty"`
+}
+
+func passCheck(name, format string, args ...interface{}) Check {
+	return Check{Name: name, Status: CheckPass, Message: fmt.Sprintf(format, args...)}
+}
+
+func warnCheck(name, hint, format string, args ...interface{}) Check {
+	return Check{Name: name, Status: CheckWarn, Message: fmt.Sprintf(format, args...), Hint: hint}
+}
+
+func failCheck(name, hint, format string, args ...interface{}) Check {
+	return Check{Name: name, Status: CheckFail, Message: fmt.Sprintf(format, args...), Hint: hint}
+}
+
+// RunChecks runs the doctor checks in order: config, project config,
+// network settings, API, credentials, logs and terminal
+func RunChecks() []Check {
+	checks := []Check{
+		checkConfigFile(),
+		checkProjectConfig(),
+		checkNetworkSettings(),
+	}
+	api := checkAPI()
+	checks = append(checks, api, checkCredentials(api.Status == CheckPass), checkLogDir(), checkTerminal())
+	return checks
+}
+
+// checkConfigFile checks the location, permissions and contents of the user
+// config file
+func checkConfigFile() Check {
+	const name = "config"
+	path := ConfigFile()
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return passCheck(name, "No config file at %s, using defaults", path)
+	}
+	if err != nil {
+		return failCheck(name, "Check the permissions of the config directory", "Can't read %s: %v", path, err)
+	}
+
+	problems, err := ValidateConfigFile()
+	if err != nil {
+		return failCheck(name, "Fix the YAML with 'snippetkit config edit'", "%v", err)
+	}
+	// The config file may hold tokens
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		check := warnCheck(name, "Run: chmod 600 "+path, "%s is readable by other users (%s)", path, info.Mode().Perm())
+		check.Details = errorStrings(problems)
+		return check
+	}
+	if len(problems) > 0 {
+		check := warnCheck(name, "Fix or remove the settings with 'snippetkit config edit' or 'snippetkit config unset'",
+			"%s has %d invalid setting(s)", path, len(problems))
+		check.Details = errorStrings(problems)
+		return check
+	}
+	return passCheck(name, "%s is valid", path)
+}
+
+// checkProjectConfig checks the project config file in use: its settings,
+// that it doesn't hold tokens that could be committed or settings only the
+// user config may set, and that the paths it refers to exist
+func checkProjectConfig() Check {
+	const name = "project config"
+	path := ProjectConfigFile()
+	if path == "" {
+		return passCheck(name, "No %s in this directory or its parents", projectConfigName)
+	}
+	problems, err := validateConfig(path)
+	if err != nil {
+		return failCheck(name, "Fix the YAML in "+path, "%v", err)
+	}
+	details := errorStrings(problems)
+	hint := "Fix the settings in " + path
+
+	config, _ := readConfigFile(path)
+	values := map[string]interface{}{}
+	flattenConfig("", config, values)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var tokens, ignored bool
+	for _, key := range keys {
+		setting, err := LookupSetting(key)
+		switch {
+		case err != nil:
+			// Reported by validateConfig
+		case setting.Secret:
+			details = append(details, fmt.Sprintf("%s holds a token and may end up in version control", key))
+			tokens = true
+		case !setting.Project:
+			details = append(details, fmt.Sprintf("%s is ignored, only the user config can set it", key))
+			ignored = true
+		}
+	}
+	switch {
+	case tokens:
+		hint = "Remove the tokens from " + path + " and run 'snippetkit login'"
+	case ignored:
+		hint = "Move the ignored settings to the user config with 'snippetkit config set'"
+	}
+	if dir, ok := values["add.path"].(string); ok && dir != "" {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(path), dir)
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			details = append(details, fmt.Sprintf("add.path %s is not a directory", dir))
+		}
+	}
+
+	if len(details) > 0 {
+		check := warnCheck(name, hint, "%s has %d problem(s)", path, len(details))
+		check.Details = details
+		return check
+	}
+	return passCheck(name, "%s is valid", path)
+}
+
+// checkNetworkSettings checks the proxy and CA certificate environment
+func checkNetworkSettings() Check {
+	const name = "proxy/CA"
+	var details []string
+	for _, key := range []string{"HTTPS_PROXY", "HTTP_PROXY", "NO_PROXY", "SSL_CERT_FILE", "SSL_CERT_DIR"} {
+		if value := envAnyCase(key); value != "" {
+			details = append(details, key+"="+redactURL(value))
+		}
+	}
+
+	req, err := http.NewRequest("GET", BaseURL(), nil)
+	if err != nil {
+		return failCheck(name, "Fix base_url with 'snippetkit config set'", "Invalid API URL %s: %v", BaseURL(), err)
+	}
+	proxy, err := http.ProxyFromEnvironment(req)
+	if err != nil {
+		check := failCheck(name, "Fix or unset HTTPS_PROXY", "Invalid proxy: %v", err)
+		check.Details = details
+		return check
+	}
+
+	if file := os.Getenv("SSL_CERT_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			check := failCheck(name, "Point SSL_CERT_FILE at a readable PEM file or unset it", "Can't read SSL_CERT_FILE: %v", err)
+			check.Details = details
+			return check
+		}
+		if block, _ := pem.Decode(data); block == nil {
+			check := failCheck(name, "Point SSL_CERT_FILE at a PEM file or unset it", "SSL_CERT_FILE %s has no PEM certificates", file)
+			check.Details = details
+			return check
+		}
+	}
+	if dir := os.Getenv("SSL_CERT_DIR"); dir != "" {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			check := warnCheck(name, "Point SSL_CERT_DIR at a directory or unset it", "SSL_CERT_DIR %s is not a directory", dir)
+			check.Details = details
+			return check
+		}
+	}
+	if _, err := x509.SystemCertPool(); err != nil {
+		check := warnCheck(name, "Install your system's CA certificates or set SSL_CERT_FILE", "Can't load system CA certificates: %v", err)
+		check.Details = details
+		return check
+	}
+
+	message := "No proxy for " + BaseURL()
+	if proxy != nil {
+		message = "Using proxy " + redactURL(proxy.String())
+	}
+	check := passCheck(name, "%s", message)
+	check.Details = details
+	return check
+}
+
+// checkAPI checks that the API answers and how fast
+func checkAPI() Check {
+	const name = "api"
+	if Offline() {
+		return warnCheck(name, "Run without --offline to check the API", "Skipped, offline mode is on")
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	start := time.Now()
+	resp, err := client.Get(BaseURL() + "/api/token/verify")
+	latency := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		recordHTTP("GET", BaseURL()+"/api/token/verify", start, 0, 0, err)
+		return failCheck(name, "Check your connection, proxy and base_url", "Can't reach %s: %v", BaseURL(), err)
+	}
+	resp.Body.Close()
+	recordHTTP("GET", BaseURL()+"/api/token/verify", start, resp.StatusCode, 0, nil)
+	if resp.StatusCode >= 500 {
+		return failCheck(name, "The service may be down, try again later", "%s answered with status %d in %s", BaseURL(), resp.StatusCode, latency)
+	}
+	if latency > slowAPILatency {
+		return warnCheck(name, "Check your connection or proxy", "%s is slow to answer (%s)", BaseURL(), latency)
+	}
+	return passCheck(name, "%s answered in %s", BaseURL(), latency)
+}
+
+// checkCredentials checks that the active profile has a token and, when the
+// API is reachable, that it is valid
+func checkCredentials(verify bool) Check {
+	const name = "credentials"
+	profile, err := ActiveProfile()
+	if err != nil {
+		return failCheck(name, "Pick an existing profile with 'snippetkit profile use'", "%v", err)
+	}
+	token, source, err := LoadAPIKey(profile)
+	if errors.Is(err, ErrNoInput) {
+		return warnCheck(name, "Set "+EnvVar("passphrase")+" to unlock the credentials file", "Can't unlock the credentials of profile %s", profile.Name)
+	}
+	if err != nil {
+		return failCheck(name, "Run 'snippetkit login' to store the token again", "%v", err)
+	}
+	if token == "" {
+		return failCheck(name, "Run 'snippetkit login'", "Profile %s is not logged in", profile.Name)
+	}
+	if !verify {
+		return warnCheck(name, "Fix the API check first", "Profile %s has a token from %s, not verified", profile.Name, TokenSourceDescription(source))
+	}
+
+	info, err := InspectToken(token)
+	if errors.Is(err, ErrUnauthorized) {
+		return failCheck(name, "Run 'snippetkit login' to get a new token", "The token of profile %s is invalid or expired", profile.Name)
+	}
+	if err != nil {
+		return warnCheck(name, "Try again later", "Couldn't verify the token of profile %s: %v", profile.Name, err)
+	}
+	if info.ExpiresAt != nil && time.Until(*info.ExpiresAt) < 7*24*time.Hour {
+		return warnCheck(name, "Run 'snippetkit login' to renew it", "The token of profile %s expires %s", profile.Name, info.ExpiresAt.Local().Format(time.RFC1123))
+	}
+	return passCheck(name, "Profile %s has a valid token from %s", profile.Name, TokenSourceDescription(source))
+}
+
+// checkLogDir checks that log files can be written
+func checkLogDir() Check {
+	const name = "logs"
+	dir := LogDir()
+	if !viper.GetBool("logging_enabled") {
+		return passCheck(name, "File logging is off")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return failCheck(name, "Fix the permissions of "+filepath.Dir(dir)+" or run 'snippetkit config set logging_enabled false'", "Can't create %s: %v", dir, err)
+	}
+	file, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return failCheck(name, "Fix the permissions of "+dir+" or run 'snippetkit config set logging_enabled false'", "%s is not writable: %v", dir, err)
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return passCheck(name, "%s is writable", dir)
+}
+
+// checkTerminal reports what the terminal supports
+func checkTerminal() Check {
+	const name = "terminal"
+	details := []string{
+		fmt.Sprintf("stdin terminal: %t", IsTerminal(os.Stdin)),
+		fmt.Sprintf("stdout terminal: %t", IsTerminal(os.Stdout)),
+		fmt.Sprintf("stderr terminal: %t", IsTerminal(os.Stderr)),
+		"TERM=" + os.Getenv("TERM"),
+	}
+	if value, ok := os.LookupEnv("NO_COLOR"); ok {
+		details = append(details, "NO_COLOR="+value)
+	}
+	if IsTerminal(os.Stdout) {
+		width, height := TerminalSize()
+		details = append(details, fmt.Sprintf("size: %dx%d", width, height))
+	}
+
+	if IsTerminal(os.Stdout) && (os.Getenv("TERM") == "" || os.Getenv("TERM") == "dumb") {
+		check := warnCheck(name, "Set TERM, e.g. TERM=xterm-256color, for colors and the interactive browser", "TERM is %q, colors and prompts may not work", os.Getenv("TERM"))
+		check.Details = details
+		return check
+	}
+	check := passCheck(name, "Colors: %s on stdout, %s on stderr, prompts: %s",
+		StreamColorProfile(os.Stdout), StreamColorProfile(os.Stderr), enabledString(CanPrompt()))
+	check.Details = details
+	return check
+}
+
+func enabledString(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
+func errorStrings(errs []error) []string {
+	values := make([]string, len(errs))
+	for i, err := range errs {
+		values[i] = err.Error()
+	}
+	return values
+}
+
+// envAnyCase reads an environment variable in upper or lower case, like the
+// proxy variables
+func envAnyCase(key string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return os.Getenv(strings.ToLower(key))
+}
+
+// redactURL hides the password of a URL, e.g. in a proxy setting
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	return u.Redacted()
+}
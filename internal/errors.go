@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnauthorized is returned when the API token is missing, invalid or expired
+	ErrUnauthorized = errors.New("authentication required")
+	// ErrNotFound is returned when the requested snippet does not exist
+	ErrNotFound = errors.New("snippet not found")
+)
+
+// NetworkError is returned when the SnippetKit API can't be reached
+type NetworkError struct {
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("failed to connect to API: %v", e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// APIError is returned when the API answers with an unexpected status or an
+// unsuccessful response
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("API request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("API request failed: %s", e.Message)
+}
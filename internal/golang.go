@@ -0,0 +1,236 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// IsGoSnippet reports whether a snippet should be installed as Go source
+func IsGoSnippet(language, path string) bool {
+	switch strings.ToLower(language) {
+	case "go", "golang":
+		return true
+	}
+	return strings.HasSuffix(path, ".go")
+}
+
+// DetectGoPackage returns the package name used by the Go files in dir.
+// An empty name is returned when the directory has no Go files.
+func DetectGoPackage(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read directory: %v", err)
+	}
+
+	fset := token.NewFileSet()
+	counts := map[string]int{}
+	var testPkg string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.PackageClauseOnly)
+		if err != nil {
+			continue // Ignore files that don't parse
+		}
+		// External test packages don't decide the package of the directory
+		if strings.HasSuffix(name, "_test.go") {
+			testPkg = strings.TrimSuffix(f.Name.Name, "_test")
+			continue
+		}
+		counts[f.Name.Name]++
+	}
+
+	// Pick the package declared by most files
+	var pkg string
+	for name, n := range counts {
+		if n > counts[pkg] || (n == counts[pkg] && name < pkg) {
+			pkg = name
+		}
+	}
+	if pkg == "" {
+		pkg = testPkg
+	}
+	return pkg, nil
+}
+
+// parseGoSnippet parses Go source, adding a package clause when the snippet is
+// only a fragment of declarations
+func parseGoSnippet(src string) (*token.FileSet, *ast.File, []byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err == nil {
+		return fset, f, []byte(src), nil
+	}
+
+	withClause := "package main\n\n" + src
+	fset = token.NewFileSet()
+	f, fragErr := parser.ParseFile(fset, "", withClause, parser.ParseComments)
+	if fragErr != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse Go source: %v", err)
+	}
+	return fset, f, []byte(withClause), nil
+}
+
+// SetGoPackage rewrites the package clause of src to pkg and formats the result
+func SetGoPackage(src, pkg string) (string, error) {
+	fset, f, _, err := parseGoSnippet(src)
+	if err != nil {
+		return "", err
+	}
+	f.Name.Name = pkg
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return "", fmt.Errorf("failed to format Go source: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// goImport is a single import spec
+type goImport struct {
+	Name string
+	Path string
+}
+
+func fileImports(f *ast.File) []goImport {
+	var imports []goImport
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		imp := goImport{Path: path}
+		if spec.Name != nil {
+			imp.Name = spec.Name.Name
+		}
+		imports = append(imports, imp)
+	}
+	return imports
+}
+
+// declNames returns the names of the top-level declarations in f
+func declNames(f *ast.File) []string {
+	var names []string
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			name := d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				name = receiverName(d.Recv.List[0].Type) + "." + name
+			} else if name == "init" || name == "_" {
+				// A file may have any number of these
+				continue
+			}
+			names = append(names, name)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.Name != "_" {
+						names = append(names, s.Name.Name)
+					}
+				case *ast.ValueSpec:
+					for _, ident := range s.Names {
+						if ident.Name != "_" {
+							names = append(names, ident.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// importsEnd returns the offset just past the package clause and import
+// declarations of f
+func importsEnd(fset *token.FileSet, f *ast.File) int {
+	end := fset.Position(f.Name.End()).Offset
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			break
+		}
+		end = fset.Position(gen.End()).Offset
+	}
+	return end
+}
+
+// MergeGoSource inserts the declarations of snippet into the existing Go file
+// source, adding any imports it is missing, and formats the result
+func MergeGoSource(existing, snippet string) (string, error) {
+	fset := token.NewFileSet()
+	dst, err := parser.ParseFile(fset, "", existing, parser.ParseComments)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse existing file: %v", err)
+	}
+
+	snipFset, snip, snipSrc, err := parseGoSnippet(snippet)
+	if err != nil {
+		return "", err
+	}
+
+	// Refuse to declare the same identifier twice
+	declared := map[string]bool{}
+	for _, name := range declNames(dst) {
+		declared[name] = true
+	}
+	for _, name := range declNames(snip) {
+		if declared[name] {
+			return "", fmt.Errorf("declaration %s already exists in the target file", name)
+		}
+	}
+
+	// Add the imports missing from the existing file. astutil leaves the
+	// import "C" of cgo files alone, along with its preamble.
+	for _, imp := range fileImports(snip) {
+		if imp.Path == "C" {
+			return "", fmt.Errorf("cgo snippets can't be merged into an existing file")
+		}
+		astutil.AddNamedImport(fset, dst, imp.Name, imp.Path)
+	}
+	var out bytes.Buffer
+	if err := format.Node(&out, fset, dst); err != nil {
+		return "", fmt.Errorf("failed to format existing file: %v", err)
+	}
+
+	// Append everything after the snippet's imports
+	body := strings.TrimSpace(string(snipSrc[importsEnd(snipFset, snip):]))
+	if body != "" {
+		out.WriteString("\n\n")
+		out.WriteString(body)
+		out.WriteString("\n")
+	}
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("failed to format merged source: %v", err)
+	}
+	return string(formatted), nil
+}
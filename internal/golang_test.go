@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeGoSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		snippet  string
+		want     string
+		err      string
+	}{
+		{
+			name:     "no imports",
+			existing: "package util\n",
+			snippet:  "import \"strings\"\n\nfunc Upper(s string) string { return strings.ToUpper(s) }\n",
+			want:     "package util\n\nimport \"strings\"\n\nfunc Upper(s string) string { return strings.ToUpper(s) }\n",
+		},
+		{
+			name:     "single import",
+			existing: "package util\n\nimport \"fmt\"\n\nvar _ = fmt.Sprint\n",
+			snippet:  "import \"strings\"\n\nvar Lower = strings.ToLower\n",
+			want:     "package util\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n\nvar _ = fmt.Sprint\n\nvar Lower = strings.ToLower\n",
+		},
+		{
+			name:     "one line import block",
+			existing: "package util\n\nimport (\"fmt\")\n\nvar _ = fmt.Sprint\n",
+			snippet:  "import \"os\"\n\nvar Args = os.Args\n",
+			want:     "package util\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nvar _ = fmt.Sprint\n\nvar Args = os.Args\n",
+		},
+		{
+			name:     "grouped imports",
+			existing: "package util\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nvar _, _ = fmt.Sprint, os.Args\n",
+			snippet:  "package other\n\nimport (\n\t\"fmt\"\n\tstr \"strings\"\n)\n\nvar Trim = str.TrimSpace\nvar Print = fmt.Println\n",
+			want:     "package util\n\nimport (\n\t\"fmt\"\n\t\"os\"\n\tstr \"strings\"\n)\n\nvar _, _ = fmt.Sprint, os.Args\n\nvar Trim = str.TrimSpace\nvar Print = fmt.Println\n",
+		},
+		{
+			name:     "cgo",
+			existing: "package util\n\n// #include <stdlib.h>\nimport \"C\"\n\nfunc Free() {}\n",
+			snippet:  "import \"fmt\"\n\nfunc Hello() { fmt.Println(\"hello\") }\n",
+			want:     "package util\n\n// #include <stdlib.h>\nimport \"C\"\nimport \"fmt\"\n\nfunc Free() {}\n\nfunc Hello() { fmt.Println(\"hello\") }\n",
+		},
+		{
+			name:     "cgo snippet",
+			existing: "package util\n",
+			snippet:  "// #include <stdlib.h>\nimport \"C\"\n\nfunc Free() {}\n",
+			err:      "cgo",
+		},
+		{
+			name:     "init and blank declarations",
+			existing: "package util\n\nfunc init() {}\n\nvar _ = 1\n",
+			snippet:  "func init() {}\n\nvar _ = 2\n\nfunc _() {}\n",
+			want:     "package util\n\nfunc init() {}\n\nvar _ = 1\n\nfunc init() {}\n\nvar _ = 2\n\nfunc _() {}\n",
+		},
+		{
+			name:     "duplicate function",
+			existing: "package util\n\nfunc Upper() {}\n",
+			snippet:  "func Upper() {}\n",
+			err:      "declaration Upper already exists",
+		},
+		{
+			name:     "duplicate method",
+			existing: "package util\n\ntype T struct{}\n\nfunc (t *T) init() {}\n",
+			snippet:  "func (T) init() {}\n",
+			err:      "declaration T.init already exists",
+		},
+		{
+			name:     "duplicate type",
+			existing: "package util\n\ntype Config struct{}\n",
+			snippet:  "type Config = map[string]string\n",
+			err:      "declaration Config already exists",
+		},
+		{
+			name:     "method on another type",
+			existing: "package util\n\ntype A struct{}\n\nfunc (A) Close() {}\n",
+			snippet:  "type B struct{}\n\nfunc (*B) Close() {}\n",
+			want:     "package util\n\ntype A struct{}\n\nfunc (A) Close() {}\n\ntype B struct{}\n\nfunc (*B) Close() {}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergeGoSource(tt.existing, tt.snippet)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("MergeGoSource() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("MergeGoSource() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
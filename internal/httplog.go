@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// maxHTTPHistory is the number of requests kept in the HTTP history
+const maxHTTPHistory = 100
+
+// HTTPSummary describes an API request without its headers or body, so it
+// can be shared in a debug bundle
+type HTTPSummary struct {
+	Time     time.Time `json:"time"`
+	Method   string    `json:"method"`
+	URL      string    `json:"url"`
+	Status   int       `json:"status,omitempty"`
+	Duration string    `json:"duration"`
+	Bytes    int       `json:"bytes"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// HTTPHistoryFile returns the location of the HTTP history
+func HTTPHistoryFile() string {
+	return filepath.Join(StateDir(), "http-history.jsonl")
+}
+
+// recordHTTP adds a request to the HTTP history. Like the log files, the
+// history is only kept when logging_enabled is set.
+func recordHTTP(method, rawURL string, start time.Time, status, size int, err error) {
+	if !viper.GetBool("logging_enabled") {
+		return
+	}
+	summary := HTTPSummary{
+		Time:     start,
+		Method:   method,
+		URL:      redactURL(rawURL),
+		Status:   status,
+		Duration: time.Since(start).Round(time.Millisecond).String(),
+		Bytes:    size,
+	}
+	if u, parseErr := url.Parse(rawURL); parseErr == nil {
+		// Query strings may hold search terms, keep the parameter names only
+		query := u.Query()
+		for key := range query {
+			query[key] = []string{"..."}
+		}
+		u.RawQuery = query.Encode()
+		summary.URL = redactURL(u.String())
+	}
+	if err != nil {
+		// *url.Error messages repeat the request URL, query included
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		summary.Error = strings.ReplaceAll(err.Error(), rawURL, summary.URL)
+	}
+
+	line, marshalErr := json.Marshal(summary)
+	if marshalErr != nil {
+		return
+	}
+	history := append(readHTTPHistoryLines(), line)
+	if len(history) > maxHTTPHistory {
+		history = history[len(history)-maxHTTPHistory:]
+	}
+	path := HTTPHistoryFile()
+	if err := EnsureDirExists(path); err != nil {
+		Debug("Failed to create state directory", map[string]interface{}{"error": err.Error()})
+		return
+	}
+	data := append(bytes.Join(history, []byte("\n")), '\n')
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		Debug("Failed to write HTTP history", map[string]interface{}{"error": err.Error()})
+	}
+}
+
+func readHTTPHistoryLines() [][]byte {
+	file, err := os.Open(HTTPHistoryFile())
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	var lines [][]byte
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+		}
+	}
+	return lines
+}
+
+// HTTPHistory returns the last n recorded API requests, oldest first
+func HTTPHistory(n int) []HTTPSummary {
+	lines := readHTTPHistoryLines()
+	if n >= 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	summaries := make([]HTTPSummary, 0, len(lines))
+	for _, line := range lines {
+		var summary HTTPSummary
+		if err := json.Unmarshal(line, &summary); err == nil {
+			summaries = append(summaries, summary)
+		}
+	}
+	return summaries
+}
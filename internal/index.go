@@ -0,0 +1,496 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Field weights, a term in the title counts as much as three in the code
+const (
+	weightTitle       = 3
+	weightTags        = 2
+	weightDescription = 1
+	weightCode        = 1
+)
+
+// Scores of approximate term matches relative to exact ones
+const (
+	prefixMatchScore = 0.7
+	fuzzyMatchScore  = 0.5
+)
+
+// indexDoc is a snippet in the search index
+type indexDoc struct {
+	Source  string         `json:"source"`
+	Snippet Snippet        `json:"snippet"` // Without code to keep the index small
+	Terms   map[string]int `json:"terms"`   // Weighted term frequencies
+	Length  int            `json:"length"`
+}
+
+// SearchIndex is an inverted index over cached and local snippets. Sources
+// (cache entries and local files) are tracked by signature so only the ones
+// that changed are re-indexed.
+type SearchIndex struct {
+	Sources  map[string]string         `json:"sources"`  // Source key -> signature
+	Docs     map[string]*indexDoc      `json:"docs"`     // Doc ID -> document
+	Postings map[string]map[string]int `json:"postings"` // Term -> doc ID -> frequency
+	path     string
+}
+
+// searchIndexPath returns the location of the search index of the active
+// profile
+func searchIndexPath() string {
+	sum := sha256.Sum256([]byte(cacheScope()))
+	return filepath.Join(CacheDir(), "search-index-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// Tokenize splits text into lowercase search terms
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := fields[:0]
+	for _, field := range fields {
+		if len([]rune(field)) >= 2 {
+			terms = append(terms, field)
+		}
+	}
+	return terms
+}
+
+// OpenSearchIndex loads the search index and brings it up to date with the
+// cache and the local snippets directory
+func OpenSearchIndex() (*SearchIndex, error) {
+	idx := &SearchIndex{
+		Sources:  map[string]string{},
+		Docs:     map[string]*indexDoc{},
+		Postings: map[string]map[string]int{},
+		path:     searchIndexPath(),
+	}
+
+	data, err := os.ReadFile(idx.path)
+	if err == nil {
+		if err := json.Unmarshal(data, idx); err != nil {
+			Warn("Search index is corrupt, rebuilding", map[string]interface{}{"error": err.Error()})
+			idx.Sources, idx.Docs, idx.Postings = map[string]string{}, map[string]*indexDoc{}, map[string]map[string]int{}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read search index: %v", err)
+	}
+
+	changed, err := idx.update()
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		if err := idx.save(); err != nil {
+			Warn("Failed to save search index", map[string]interface{}{"error": err.Error()})
+		}
+	}
+	return idx, nil
+}
+
+// save writes the index atomically
+func (idx *SearchIndex) save() error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("failed to encode search index: %v", err)
+	}
+	if err := EnsureDirExists(idx.path); err != nil {
+		return err
+	}
+	tmp := idx.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write search index: %v", err)
+	}
+	return os.Rename(tmp, idx.path)
+}
+
+// indexSource is a unit of indexing: a cache entry or a local file
+type indexSource struct {
+	signature string
+	load      func() ([]Snippet, error)
+}
+
+// currentSources lists the sources that should be in the index
+func currentSources() (map[string]indexSource, error) {
+	sources := map[string]indexSource{}
+
+	cache, err := OpenCache()
+	if err != nil {
+		return nil, err
+	}
+	scope := cacheScope()
+	for _, entry := range cache.Entries() {
+		key := entry.Key
+		// Other profiles' responses stay out of this profile's index
+		if !strings.HasSuffix(key, scope) {
+			continue
+		}
+		sources["cache:"+key] = indexSource{
+			signature: entry.Hash,
+			load: func() ([]Snippet, error) {
+				data, err := cache.Peek(key)
+				if err != nil || data == nil {
+					return nil, err
+				}
+				return decodeCachedSnippets(key, data)
+			},
+		}
+	}
+
+	dir := LocalSnippetsDir()
+	files, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read local snippets: %v", err)
+	}
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil || file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		sources["file:"+path] = indexSource{
+			signature: fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()),
+			load: func() ([]Snippet, error) {
+				snippet, err := LoadLocalSnippet(path)
+				if err != nil {
+					return nil, err
+				}
+				return []Snippet{*snippet}, nil
+			},
+		}
+	}
+	return sources, nil
+}
+
+// decodeCachedSnippets extracts the snippets of a cached API response
+func decodeCachedSnippets(key string, data []byte) ([]Snippet, error) {
+	if strings.HasPrefix(key, "search:") {
+		var resp APIResponseMultiple
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, err
+		}
+		return resp.Data, nil
+	}
+	var resp APIResponseSingle
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return []Snippet{resp.Data}, nil
+}
+
+// LoadLocalSnippet reads a snippet from a local file
+func LoadLocalSnippet(path string) (*Snippet, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local snippet: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local snippet: %v", err)
+	}
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	return &Snippet{
+		ID:        "local:" + name,
+		ShortID:   "local:" + name,
+		Title:     strings.TrimSuffix(name, ext),
+		Language:  strings.TrimPrefix(ext, "."),
+		Path:      name,
+		Code:      string(code),
+		Tags:      []string{"local"},
+		UpdatedAt: info.ModTime(),
+	}, nil
+}
+
+// update re-indexes the sources that changed since the index was written and
+// reports whether anything changed
+func (idx *SearchIndex) update() (bool, error) {
+	sources, err := currentSources()
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	for key, signature := range idx.Sources {
+		if source, ok := sources[key]; !ok || source.signature != signature {
+			idx.removeSource(key)
+			changed = true
+		}
+	}
+	for key, source := range sources {
+		if _, ok := idx.Sources[key]; ok {
+			continue
+		}
+		snippets, err := source.load()
+		if err != nil {
+			Warn("Failed to index snippet source", map[string]interface{}{"source": key, "error": err.Error()})
+			continue
+		}
+		for i := range snippets {
+			idx.addDoc(fmt.Sprintf("%s#%d", key, i), key, snippets[i])
+		}
+		idx.Sources[key] = source.signature
+		changed = true
+	}
+	return changed, nil
+}
+
+func (idx *SearchIndex) removeSource(key string) {
+	for id, doc := range idx.Docs {
+		if doc.Source != key {
+			continue
+		}
+		for term := range doc.Terms {
+			delete(idx.Postings[term], id)
+			if len(idx.Postings[term]) == 0 {
+				delete(idx.Postings, term)
+			}
+		}
+		delete(idx.Docs, id)
+	}
+	delete(idx.Sources, key)
+}
+
+func (idx *SearchIndex) addDoc(id, source string, snippet Snippet) {
+	terms := map[string]int{}
+	length := 0
+	addField := func(text string, weight int) {
+		for _, term := range Tokenize(text) {
+			terms[term] += weight
+			length += weight
+		}
+	}
+	addField(snippet.Title, weightTitle)
+	addField(strings.Join(snippet.Tags, " "), weightTags)
+	addField(snippet.Description, weightDescription)
+	addField(snippet.Code, weightCode)
+
+	snippet.Code = ""
+	idx.Docs[id] = &indexDoc{Source: source, Snippet: snippet, Terms: terms, Length: length}
+	for term, tf := range terms {
+		if idx.Postings[term] == nil {
+			idx.Postings[term] = map[string]int{}
+		}
+		idx.Postings[term][id] = tf
+	}
+}
+
+// SearchHit is a ranked result of a local search
+type SearchHit struct {
+	Snippet Snippet
+	Score   float64
+}
+
+// Search ranks the indexed snippets against the query terms with BM25. Terms
+// missing from the index fall back to prefix and typo tolerant matches.
+func (idx *SearchIndex) Search(query string) []SearchHit {
+	if len(idx.Docs) == 0 {
+		return nil
+	}
+
+	totalLength := 0
+	for _, doc := range idx.Docs {
+		totalLength += doc.Length
+	}
+	avgLength := float64(totalLength) / float64(len(idx.Docs))
+
+	scores := map[string]float64{}
+	for _, term := range Tokenize(query) {
+		for match, weight := range idx.expand(term) {
+			postings := idx.Postings[match]
+			df := float64(len(postings))
+			idf := math.Log(1 + (float64(len(idx.Docs))-df+0.5)/(df+0.5))
+			for id, tf := range postings {
+				norm := 1 - bm25B + bm25B*float64(idx.Docs[id].Length)/avgLength
+				f := float64(tf)
+				scores[id] += weight * idf * f * (bm25K1 + 1) / (f + bm25K1*norm)
+			}
+		}
+	}
+
+	// Keep the best hit per snippet, the same snippet can be cached more than once
+	best := map[string]SearchHit{}
+	for id, score := range scores {
+		snippet := idx.Docs[id].Snippet
+		key := snippet.ShortID
+		if key == "" {
+			key = id
+		}
+		if hit, ok := best[key]; !ok || score > hit.Score {
+			best[key] = SearchHit{Snippet: snippet, Score: score}
+		}
+	}
+
+	hits := make([]SearchHit, 0, len(best))
+	for _, hit := range best {
+		hits = append(hits, hit)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Snippet.Title < hits[j].Snippet.Title
+	})
+	return hits
+}
+
+// all returns every indexed snippet, ordered by title
+func (idx *SearchIndex) all() []SearchHit {
+	best := map[string]SearchHit{}
+	for id, doc := range idx.Docs {
+		key := doc.Snippet.ShortID
+		if key == "" {
+			key = id
+		}
+		best[key] = SearchHit{Snippet: doc.Snippet}
+	}
+
+	hits := make([]SearchHit, 0, len(best))
+	for _, hit := range best {
+		hits = append(hits, hit)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].Snippet.Title < hits[j].Snippet.Title
+	})
+	return hits
+}
+
+// loadIndexedCode returns the code of an indexed snippet from its local file
+// or the cached snippet response, or "" when neither is available
+func loadIndexedCode(snippet *Snippet) string {
+	if name, ok := strings.CutPrefix(snippet.ShortID, "local:"); ok {
+		local, err := LoadLocalSnippet(filepath.Join(LocalSnippetsDir(), filepath.Base(name)))
+		if err != nil {
+			return ""
+		}
+		return local.Code
+	}
+
+	cache, err := OpenCache()
+	if err != nil {
+		return ""
+	}
+	key := snippetCacheKey(snippet.ShortID)
+	data, err := cache.Peek(key)
+	if err != nil || data == nil {
+		return ""
+	}
+	cached, err := decodeCachedSnippets(key, data)
+	if err != nil || len(cached) == 0 {
+		return ""
+	}
+	return cached[0].Code
+}
+
+// expand maps a query term to the indexed terms it matches and their weights
+func (idx *SearchIndex) expand(term string) map[string]float64 {
+	matches := map[string]float64{}
+	if _, ok := idx.Postings[term]; ok {
+		matches[term] = 1
+	}
+
+	n := len([]rune(term))
+	maxDistance := 1
+	if n > 5 {
+		maxDistance = 2
+	}
+	for candidate := range idx.Postings {
+		if candidate == term {
+			continue
+		}
+		if n >= 3 && strings.HasPrefix(candidate, term) {
+			matches[candidate] = math.Max(matches[candidate], prefixMatchScore)
+			continue
+		}
+		if n >= 4 && levenshtein(term, candidate, maxDistance) <= maxDistance {
+			matches[candidate] = math.Max(matches[candidate], fuzzyMatchScore)
+		}
+	}
+	return matches
+}
+
+// levenshtein returns the edit distance between a and b, giving up early with
+// max+1 once the distance is known to exceed max
+func levenshtein(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > max || -diff > max {
+		return max + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// SearchLocal returns page number page (starting at 1) of limit results of a
+// query against the cached and local snippets. A limit of zero returns every
+// result.
+func SearchLocal(q *Query, page, limit int) (*SearchPage, error) {
+	idx, err := OpenSearchIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	hits := idx.Search(q.Text())
+	if q.Text() == "" {
+		// Only filters were given, consider every snippet
+		hits = idx.all()
+	}
+
+	var snippets []Snippet
+	for _, hit := range hits {
+		if q.matchesText() && hit.Snippet.Code == "" {
+			// The index leaves out the code, phrases have to see it
+			hit.Snippet.Code = loadIndexedCode(&hit.Snippet)
+		}
+		if q.Matches(&hit.Snippet) {
+			snippets = append(snippets, hit.Snippet)
+		}
+	}
+
+	return PageOf(snippets, page, limit), nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
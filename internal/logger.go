@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -17,41 +18,67 @@ var loggingEnabled bool
 // InitLogger initializes the logger with proper settings
 func InitLogger() {
     // Load logging enabled setting from config (default: true)
-    loggingEnabled = viper.GetBool("logging_enabled")
+    fileLogging := viper.GetBool("logging_enabled")
+    // Verbose mode echoes log lines to stderr
+    verbose := viper.GetBool("verbose")
+    loggingEnabled = fileLogging || verbose
 
     if !loggingEnabled {
         return
     }
 
-    // Ensure logs directory exists
-    logDir := filepath.Join(os.Getenv("HOME"), ".config/snippetkit/logs")
-    if err := os.MkdirAll(logDir, 0755); err != nil {
-        fmt.Println("⚠️ Failed to create logs directory:", err)
-        return
+    var writers []io.Writer
+    if verbose {
+        writers = append(writers, os.Stderr)
     }
 
-    // Define log file with rotation based on date
-    logFile := filepath.Join(logDir, fmt.Sprintf("snippetkit-%s.log", time.Now().Format("2006-01-02")))
-    file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-    if err != nil {
-        fmt.Println("⚠️ Failed to open log file:", err)
+    if fileLogging {
+        if file, err := openLogFile(); err != nil {
+            fmt.Fprintln(os.Stderr, "⚠️", err)
+        } else {
+            writers = append(writers, file)
+        }
+    }
+
+    if len(writers) == 0 {
+        loggingEnabled = false
         return
     }
 
-    // Configure log output (console + file)
-    log.SetOutput(file)
+    // Configure log output (file and/or stderr)
+    log.SetOutput(io.MultiWriter(writers...))
 
     // Set log format
     log.SetFormatter(&logrus.TextFormatter{
         FullTimestamp: true,
-        ForceColors:   true,
+        ForceColors:   !verbose || IsTerminal(os.Stderr),
     })
 
-    // Load log level from config (default: INFO)
+    // Load log level from config (default: INFO, debug when verbose)
     logLevel := viper.GetString("log_level")
+    if verbose {
+        logLevel = "debug"
+    }
     SetLogLevel(logLevel)
 }
 
+// openLogFile opens today's log file for appending
+func openLogFile() (*os.File, error) {
+    // Ensure logs directory exists
+    logDir := LogDir()
+    if err := os.MkdirAll(logDir, 0755); err != nil {
+        return nil, fmt.Errorf("failed to create logs directory: %v", err)
+    }
+
+    // Define log file with rotation based on date
+    logFile := filepath.Join(logDir, fmt.Sprintf("snippetkit-%s.log", time.Now().Format("2006-01-02")))
+    file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open log file: %v", err)
+    }
+    return file, nil
+}
+
 // SetLogLevel dynamically updates the log level
 func SetLogLevel(level string) {
     if !loggingEnabled {
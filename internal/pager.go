@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultPager        = "less -R"
+	defaultPreviewLines = 10
+)
+
+// PreviewLines returns how many lines of code info shows without --full
+func PreviewLines() int {
+	if !viper.IsSet("preview_lines") {
+		return defaultPreviewLines
+	}
+	lines := viper.GetInt("preview_lines")
+	if lines <= 0 {
+		Warn("Invalid preview_lines, using default", map[string]interface{}{"value": viper.Get("preview_lines")})
+		return defaultPreviewLines
+	}
+	return lines
+}
+
+// Pager returns the pager command from $PAGER, or less -R
+func Pager() string {
+	if pager := strings.TrimSpace(os.Getenv("PAGER")); pager != "" {
+		return pager
+	}
+	return defaultPager
+}
+
+// PageOutput writes text to stdout, through the pager when stdout is a
+// terminal and the text is taller than it. If the pager can't be started the
+// text is printed directly.
+func PageOutput(text string) error {
+	_, height := TerminalSize()
+	if !IsTerminal(os.Stdout) || height == 0 || strings.Count(text, "\n") < height {
+		_, err := fmt.Fprint(Stdout, text)
+		return err
+	}
+
+	args := strings.Fields(Pager())
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if _, isExit := err.(*exec.ExitError); isExit {
+			// The pager ran, e.g. it was quit early
+			return nil
+		}
+		Warn("Failed to start pager, printing directly", map[string]interface{}{"pager": Pager(), "error": err.Error()})
+		_, err := fmt.Fprint(Stdout, text)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+// appName names the snippetkit directories in the XDG base directories
+const appName = "snippetkit"
+
+// homeDir returns the user's home directory
+func homeDir() string {
+	if home, err := os.UserHomeDir(); err == nil {
+		return home
+	}
+	return os.Getenv("HOME")
+}
+
+// xdgDir returns $<env>/snippetkit, or fallback under the home directory when
+// the variable is unset. Relative paths are invalid per the XDG spec and are
+// ignored.
+func xdgDir(env, fallback string) string {
+	if dir := os.Getenv(env); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName)
+	}
+	return filepath.Join(homeDir(), fallback, appName)
+}
+
+// ConfigDir returns the directory of the config file:
+// $XDG_CONFIG_HOME/snippetkit, by default ~/.config/snippetkit
+func ConfigDir() string {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// CacheDir returns the directory of the snippet cache and search index:
+// $XDG_CACHE_HOME/snippetkit, by default ~/.cache/snippetkit
+func CacheDir() string {
+	return xdgDir("XDG_CACHE_HOME", ".cache")
+}
+
+// DataDir returns the directory of user data such as local snippets:
+// $XDG_DATA_HOME/snippetkit, by default ~/.local/share/snippetkit
+func DataDir() string {
+	return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// StateDir returns the directory of logs and other state:
+// $XDG_STATE_HOME/snippetkit, by default ~/.local/state/snippetkit
+func StateDir() string {
+	return xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state"))
+}
+
+// LogDir returns the directory of the log files
+func LogDir() string {
+	return filepath.Join(StateDir(), "logs")
+}
+
+// LocalSnippetsDir returns the directory of local snippet files. Every file in
+// it is indexed as a snippet named after the file.
+func LocalSnippetsDir() string {
+	return filepath.Join(DataDir(), "snippets")
+}
+
+// GetConfigPath returns the config file: the one given with --config, or
+// config.yaml in ConfigDir
+func GetConfigPath() string {
+	if path := viper.GetString("config"); path != "" {
+		return path
+	}
+	return filepath.Join(ConfigDir(), "config.yaml")
+}
+
+// legacyDir is where everything was kept before the XDG directories were used
+func legacyDir() string {
+	return filepath.Join(homeDir(), ".config", appName)
+}
+
+// Migration is a file or directory moved out of the legacy directory
+type Migration struct {
+	From string
+	To   string
+}
+
+// MigrateLegacyPaths moves the config file, cache, logs and local snippets
+// from ~/.config/snippetkit to their XDG directories. Targets that already
+// exist are left alone, so the migration runs once. Files that are no longer
+// used are removed.
+func MigrateLegacyPaths() ([]Migration, error) {
+	legacy := legacyDir()
+	moves := []Migration{
+		{filepath.Join(legacy, "config.yaml"), filepath.Join(ConfigDir(), "config.yaml")},
+		{filepath.Join(legacy, "cache"), CacheDir()},
+		{filepath.Join(legacy, "logs"), LogDir()},
+		{filepath.Join(legacy, "snippets"), LocalSnippetsDir()},
+	}
+
+	var moved []Migration
+	for _, move := range moves {
+		if move.From == move.To || !FileExists(move.From) || FileExists(move.To) {
+			continue
+		}
+		if err := EnsureDirExists(move.To); err != nil {
+			return moved, fmt.Errorf("failed to create %s: %v", filepath.Dir(move.To), err)
+		}
+		if err := os.Rename(move.From, move.To); err != nil {
+			return moved, fmt.Errorf("failed to move %s to %s: %v", move.From, move.To, err)
+		}
+		moved = append(moved, move)
+	}
+
+	// The search index shared by all profiles was replaced by one per profile
+	os.Remove(filepath.Join(CacheDir(), "search-index.json"))
+	return moved, nil
+}
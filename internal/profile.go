@@ -0,0 +1,325 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultProfile is used when no profile is selected
+	DefaultProfile = "default"
+	// DefaultBaseURL is the SnippetKit API used by profiles without a base_url
+	DefaultBaseURL = "https://snippetkit.vercel.app"
+)
+
+// ErrProfileNotFound is returned for profiles missing from the config
+var ErrProfileNotFound = errors.New("profile not found")
+
+// profileNamePattern restricts profile names to what survives viper's case
+// insensitive keys
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Profile is a named account: its API token, API base URL and flag defaults.
+// Profiles live under profiles in the config file:
+//
+//	current_profile: work
+//	profiles:
+//	  work:
+//	    api_key: ...
+//	    base_url: https://snippets.example.com
+//	    defaults:
+//	      lang: go
+type Profile struct {
+	Name     string            `mapstructure:"-" json:"name" yaml:"name"`
+	APIKey   string            `mapstructure:"api_key" json:"-" yaml:"-"`
+	BaseURL  string            `mapstructure:"base_url" json:"base_url" yaml:"base_url"`
+	Defaults map[string]string `mapstructure:"defaults" json:"defaults,omitempty" yaml:"defaults,omitempty"`
+
+	// CredentialHelper stores the token elsewhere, see NewCredentialStore
+	CredentialHelper string `mapstructure:"credential_helper" json:"credential_helper,omitempty" yaml:"credential_helper,omitempty"`
+}
+
+// profileOverride is the profile selected with --profile
+var profileOverride string
+
+// SetProfileOverride selects a profile for this run, as --profile does
+func SetProfileOverride(name string) {
+	profileOverride = strings.ToLower(name)
+}
+
+// ActiveProfileName returns the selected profile: --profile, then
+// SNIPPETKIT_PROFILE, then current_profile, then default
+func ActiveProfileName() string {
+	if profileOverride != "" {
+		return profileOverride
+	}
+	if name := os.Getenv("SNIPPETKIT_PROFILE"); name != "" {
+		return strings.ToLower(name)
+	}
+	if name := viper.GetString("current_profile"); name != "" {
+		return strings.ToLower(name)
+	}
+	return DefaultProfile
+}
+
+// ValidateProfileName checks that name can be used as a profile name
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q (use lowercase letters, digits, '-' and '_')", name)
+	}
+	return nil
+}
+
+// ProfileExists reports whether a profile is configured. The default profile
+// always exists.
+func ProfileExists(name string) bool {
+	return name == DefaultProfile || viper.IsSet("profiles."+name)
+}
+
+// GetProfile returns a configured profile
+func GetProfile(name string) (*Profile, error) {
+	if !ProfileExists(name) {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	profile := &Profile{}
+	if viper.IsSet("profiles." + name) {
+		if err := viper.UnmarshalKey("profiles."+name, profile); err != nil {
+			return nil, fmt.Errorf("invalid profile %s: %v", name, err)
+		}
+	}
+	profile.Name = name
+	// Before profiles the token was stored at the top level
+	if name == DefaultProfile && profile.APIKey == "" {
+		profile.APIKey = viper.GetString("api_key")
+	}
+	if profile.BaseURL == "" {
+		profile.BaseURL = DefaultBaseURL
+	}
+	profile.BaseURL = strings.TrimRight(profile.BaseURL, "/")
+	return profile, nil
+}
+
+// ActiveProfile returns the selected profile. The base_url setting overrides
+// its API.
+func ActiveProfile() (*Profile, error) {
+	profile, err := GetProfile(ActiveProfileName())
+	if err != nil {
+		return nil, err
+	}
+	if base := viper.GetString("base_url"); base != "" {
+		profile.BaseURL = strings.TrimRight(base, "/")
+	}
+	return profile, nil
+}
+
+// Profiles returns the configured profiles sorted by name, including the
+// default profile
+func Profiles() ([]Profile, error) {
+	names := map[string]bool{DefaultProfile: true}
+	for name := range viper.GetStringMap("profiles") {
+		names[name] = true
+	}
+	var profiles []Profile
+	for name := range names {
+		get := GetProfile
+		if name == ActiveProfileName() {
+			get = func(string) (*Profile, error) { return ActiveProfile() }
+		}
+		profile, err := get(name)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, *profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles, nil
+}
+
+// BaseURL returns the API base URL of the active profile
+func BaseURL() string {
+	profile, err := ActiveProfile()
+	if err != nil {
+		return DefaultBaseURL
+	}
+	return profile.BaseURL
+}
+
+// ValidateBaseURL checks that a base URL is an absolute http(s) URL
+func ValidateBaseURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid base URL %q (expected e.g. https://snippetkit.example.com)", value)
+	}
+	return nil
+}
+
+// AddProfile adds a profile to the config file
+func AddProfile(name, baseURL string, defaults map[string]string) error {
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+	if baseURL != "" {
+		if err := ValidateBaseURL(baseURL); err != nil {
+			return err
+		}
+	}
+	return UpdateConfigFile(func(config map[string]interface{}) error {
+		profile := map[string]interface{}{}
+		if baseURL != "" {
+			profile["base_url"] = baseURL
+		}
+		if len(defaults) > 0 {
+			profile["defaults"] = defaults
+		}
+		configMap(config, "profiles")[name] = profile
+		return nil
+	})
+}
+
+// RemoveProfile removes a profile and its token from the config file. When
+// it was the current profile the default profile becomes current.
+func RemoveProfile(name string) error {
+	profile, err := GetProfile(name)
+	if err != nil {
+		return err
+	}
+	if store, err := CredentialStoreFor(profile); err == nil && store.Name() != CredentialPlaintext {
+		if err := store.Erase(profile); err != nil {
+			Warn("Failed to erase API token of removed profile", map[string]interface{}{"profile": name, "store": store.Name(), "error": err.Error()})
+		}
+	}
+	return UpdateConfigFile(func(config map[string]interface{}) error {
+		profiles := configMap(config, "profiles")
+		delete(profiles, name)
+		if len(profiles) == 0 {
+			delete(config, "profiles")
+		}
+		if name == DefaultProfile {
+			delete(config, "api_key")
+		}
+		if current, _ := config["current_profile"].(string); current == name {
+			delete(config, "current_profile")
+		}
+		return nil
+	})
+}
+
+// UseProfile makes a profile the current one
+func UseProfile(name string) error {
+	if !ProfileExists(name) {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	return UpdateConfigFile(func(config map[string]interface{}) error {
+		config["current_profile"] = name
+		return nil
+	})
+}
+
+// setProfileKey stores the API token of a profile, creating the profile if
+// needed
+func setProfileKey(name, apiKey string) error {
+	return UpdateConfigFile(func(config map[string]interface{}) error {
+		profile := configMap(configMap(config, "profiles"), name)
+		if apiKey == "" {
+			delete(profile, "api_key")
+		} else {
+			profile["api_key"] = apiKey
+		}
+		// Move a token stored before profiles into the default profile
+		if name == DefaultProfile {
+			delete(config, "api_key")
+		}
+		return nil
+	})
+}
+
+// setProfileHelper sets the credential helper of a profile
+func setProfileHelper(name, helper string) error {
+	return UpdateConfigFile(func(config map[string]interface{}) error {
+		configMap(configMap(config, "profiles"), name)["credential_helper"] = helper
+		return nil
+	})
+}
+
+// configMap returns the nested map under key, creating it if needed
+func configMap(config map[string]interface{}, key string) map[string]interface{} {
+	if m, ok := config[key].(map[string]interface{}); ok {
+		return m
+	}
+	m := map[string]interface{}{}
+	config[key] = m
+	return m
+}
+
+// configFilePath returns the config file in use, or the default location
+func configFilePath() string {
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path
+	}
+	return GetConfigPath()
+}
+
+// readConfigFile reads the settings of a config file. A missing file has no
+// settings.
+func readConfigFile(path string) (map[string]interface{}, error) {
+	config := map[string]interface{}{}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	return config, nil
+}
+
+// UpdateConfigFile applies update to the config file and reloads it. Only the
+// file's own settings are written, never flags or environment variables. The
+// file holds tokens, so it is only readable by the user.
+func UpdateConfigFile(update func(config map[string]interface{}) error) error {
+	path := configFilePath()
+	config, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := update(config); err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(config); err != nil {
+		return fmt.Errorf("failed to encode config file: %v", err)
+	}
+	enc.Close()
+	if err := EnsureDirExists(path); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	// Files created before tokens were written may be readable by others
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("failed to restrict config file permissions: %v", err)
+	}
+
+	userConfigPath = path
+	if err := readConfigFiles(); err != nil {
+		return fmt.Errorf("failed to reload config file: %v", err)
+	}
+	return nil
+}
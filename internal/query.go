@@ -0,0 +1,450 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Sort orders accepted by search
+const (
+	SortRelevance = "relevance"
+	SortUpdated   = "updated"
+	SortTitle     = "title"
+)
+
+// maxAPILimit caps how many results are requested when client side filters
+// need extra results to choose from
+const maxAPILimit = 100
+
+// Query is a parsed search query such as
+//
+//	auth middleware lang:go tag:http -tag:deprecated "exact phrase" path:handlers/
+//
+// Bare words are search terms and quoted text is matched as an exact phrase.
+// A leading '-' excludes a term, phrase or filter. Repeated tag: filters must
+// all match while comma separated values in one filter are alternatives, so
+// tag:http,grpc tag:auth means (http OR grpc) AND auth. Repeated lang: and
+// path: filters are alternatives.
+type Query struct {
+	Terms          []string
+	Phrases        []string
+	ExcludeTerms   []string
+	ExcludePhrases []string
+	Langs          []string
+	ExcludeLangs   []string
+	Tags           [][]string // All groups must match, any tag within a group
+	ExcludeTags    []string
+	Paths          []string
+	ExcludePaths   []string
+}
+
+// ParseQuery parses the query syntax described on Query
+func ParseQuery(input string) (*Query, error) {
+	q := &Query{}
+	rest := strings.TrimSpace(input)
+	for rest != "" {
+		exclude := false
+		if rest[0] == '-' {
+			exclude = true
+			rest = rest[1:]
+			// A lone '-' excludes nothing
+			if rest == "" || rest[0] == ' ' || rest[0] == '\t' {
+				rest = strings.TrimSpace(rest)
+				continue
+			}
+		}
+
+		// Quoted phrase
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in query: %s", rest)
+			}
+			phrase := strings.TrimSpace(rest[1 : end+1])
+			rest = strings.TrimSpace(rest[end+2:])
+			if phrase == "" {
+				continue
+			}
+			if exclude {
+				q.ExcludePhrases = append(q.ExcludePhrases, phrase)
+			} else {
+				q.Phrases = append(q.Phrases, phrase)
+			}
+			continue
+		}
+
+		word := rest
+		if i := strings.IndexAny(rest, " \t"); i >= 0 {
+			word, rest = rest[:i], strings.TrimSpace(rest[i:])
+		} else {
+			rest = ""
+		}
+		q.addWord(word, exclude)
+	}
+	return q, nil
+}
+
+// addWord adds a bare word or field filter to the query
+func (q *Query) addWord(word string, exclude bool) {
+	if word == "" {
+		return
+	}
+	field, value, isFilter := strings.Cut(word, ":")
+	values := splitValues(value)
+	if isFilter && len(values) > 0 {
+		switch strings.ToLower(field) {
+		case "lang", "language":
+			if exclude {
+				q.ExcludeLangs = append(q.ExcludeLangs, values...)
+			} else {
+				q.Langs = append(q.Langs, values...)
+			}
+			return
+		case "tag":
+			if exclude {
+				q.ExcludeTags = append(q.ExcludeTags, values...)
+			} else {
+				q.Tags = append(q.Tags, values)
+			}
+			return
+		case "path":
+			if exclude {
+				q.ExcludePaths = append(q.ExcludePaths, values...)
+			} else {
+				q.Paths = append(q.Paths, values...)
+			}
+			return
+		}
+	}
+
+	// Anything else, including unknown fields, is a plain term
+	if exclude {
+		q.ExcludeTerms = append(q.ExcludeTerms, word)
+	} else {
+		q.Terms = append(q.Terms, word)
+	}
+}
+
+func splitValues(value string) []string {
+	var values []string
+	for _, v := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '|' }) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// AddLang adds a language alternative, used for the --lang flag
+func (q *Query) AddLang(lang string) {
+	if lang != "" {
+		q.Langs = append(q.Langs, lang)
+	}
+}
+
+// AddTag adds a required tag, used for the --tag flag
+func (q *Query) AddTag(tag string) {
+	if values := splitValues(tag); len(values) > 0 {
+		q.Tags = append(q.Tags, values)
+	}
+}
+
+// Text returns the free text part of the query sent to the API
+func (q *Query) Text() string {
+	parts := append([]string{}, q.Terms...)
+	parts = append(parts, q.Phrases...)
+	return strings.Join(parts, " ")
+}
+
+// APIFilters returns the language and tag filters the API can apply itself. It
+// only takes single values; everything else is evaluated client side.
+func (q *Query) APIFilters() (lang, tag string) {
+	if len(q.Langs) == 1 {
+		lang = q.Langs[0]
+	}
+	for _, group := range q.Tags {
+		if len(group) == 1 {
+			return lang, group[0]
+		}
+	}
+	return lang, ""
+}
+
+// needsClientFilter reports whether results have to be filtered client side
+func (q *Query) needsClientFilter() bool {
+	lang, tag := q.APIFilters()
+	extraLangs := len(q.Langs) > 1 || (len(q.Langs) == 1 && lang == "")
+	extraTags := len(q.Tags) > 1 || (len(q.Tags) == 1 && tag == "")
+	return extraLangs || extraTags || len(q.Phrases) > 0 || len(q.ExcludeTerms) > 0 ||
+		len(q.ExcludePhrases) > 0 || len(q.ExcludeLangs) > 0 || len(q.ExcludeTags) > 0 ||
+		len(q.Paths) > 0 || len(q.ExcludePaths) > 0
+}
+
+// matchesText reports whether the query looks at the text of snippets beyond
+// ranking, which needs their code
+func (q *Query) matchesText() bool {
+	return len(q.Phrases) > 0 || len(q.ExcludePhrases) > 0 || len(q.ExcludeTerms) > 0
+}
+
+// Matches reports whether a snippet satisfies the filters, phrases and
+// exclusions of the query. Plain terms are left to the ranking.
+func (q *Query) Matches(s *Snippet) bool {
+	if len(q.Langs) > 0 && !containsFold(q.Langs, s.Language) {
+		return false
+	}
+	if containsFold(q.ExcludeLangs, s.Language) {
+		return false
+	}
+	for _, group := range q.Tags {
+		if !anyTag(s.Tags, group) {
+			return false
+		}
+	}
+	if anyTag(s.Tags, q.ExcludeTags) {
+		return false
+	}
+	path := strings.ToLower(s.Path)
+	if len(q.Paths) > 0 && !anyContains(path, q.Paths) {
+		return false
+	}
+	if anyContains(path, q.ExcludePaths) {
+		return false
+	}
+
+	text := strings.ToLower(strings.Join([]string{s.Title, s.Description, s.Code}, "\n"))
+	for _, phrase := range q.Phrases {
+		if !strings.Contains(text, strings.ToLower(phrase)) {
+			return false
+		}
+	}
+	if anyContains(text, q.ExcludePhrases) {
+		return false
+	}
+	words := Tokenize(text)
+	for _, term := range q.ExcludeTerms {
+		if containsFold(words, term) || containsFold(s.Tags, term) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyTag(tags []string, wanted []string) bool {
+	for _, tag := range wanted {
+		if hasTag(tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyContains(text string, needles []string) bool {
+	for _, needle := range needles {
+		if strings.Contains(text, strings.ToLower(needle)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchIterator streams the results of a query, fetching pages from the API
+// as they are needed and applying the filters the API can't express
+type SearchIterator struct {
+	q         *Query
+	apiKey    string
+	opts      SearchOptions
+	buffer    []Snippet
+	total     int
+	exhausted bool
+	err       error
+}
+
+// Iterate returns an iterator over the results of q, starting offset results
+// into the API results and fetching pageSize results at a time
+func (q *Query) Iterate(offset, pageSize int, apiKey string) *SearchIterator {
+	lang, tag := q.APIFilters()
+	return &SearchIterator{
+		q:      q,
+		apiKey: apiKey,
+		opts: SearchOptions{
+			Query:  q.Text(),
+			Lang:   lang,
+			Tag:    tag,
+			Limit:  pageSize,
+			Offset: offset,
+		},
+	}
+}
+
+// Next returns the next result. It returns false when there are no more
+// results or a request failed; check Err to tell them apart.
+func (it *SearchIterator) Next() (Snippet, bool) {
+	for len(it.buffer) == 0 {
+		if it.exhausted || it.err != nil {
+			return Snippet{}, false
+		}
+		it.fetch()
+	}
+	snippet := it.buffer[0]
+	it.buffer = it.buffer[1:]
+	return snippet, true
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *SearchIterator) Err() error {
+	return it.err
+}
+
+// Total returns the number of API results reported by the last page, or zero
+// when the API doesn't report it
+func (it *SearchIterator) Total() int {
+	return it.total
+}
+
+// fetch requests the next page of results
+func (it *SearchIterator) fetch() {
+	page, err := SearchSnippets(it.opts, it.apiKey)
+	if err != nil {
+		it.err = err
+		return
+	}
+	it.total = page.Total
+	if !page.HasMore || len(page.Snippets) == 0 {
+		it.exhausted = true
+	}
+	it.opts.Offset += len(page.Snippets)
+	it.opts.Cursor = page.NextCursor
+
+	snippets := page.Snippets
+	if it.q.needsClientFilter() {
+		if it.q.matchesText() {
+			// Search results may leave out the code, which phrases have to see
+			for i := range snippets {
+				if snippets[i].Code != "" {
+					continue
+				}
+				if full, err := FetchSnippet(snippets[i].ShortID, it.apiKey); err == nil {
+					snippets[i].Code = full.Code
+				}
+			}
+		}
+		snippets = it.q.filter(snippets)
+	}
+	it.buffer = append(it.buffer, snippets...)
+}
+
+// filter keeps the snippets matching the query
+func (q *Query) filter(snippets []Snippet) []Snippet {
+	var matched []Snippet
+	for i := range snippets {
+		if q.Matches(&snippets[i]) {
+			matched = append(matched, snippets[i])
+		}
+	}
+	return matched
+}
+
+// SearchRemoteAll returns every result of a query
+func SearchRemoteAll(q *Query, apiKey string) ([]Snippet, error) {
+	it := q.Iterate(0, maxAPILimit, apiKey)
+	var snippets []Snippet
+	for snippet, ok := it.Next(); ok; snippet, ok = it.Next() {
+		snippets = append(snippets, snippet)
+	}
+	return snippets, it.Err()
+}
+
+// PageOf returns page number page (starting at 1) of limit snippets, or all
+// of them when limit is zero
+func PageOf(snippets []Snippet, page, limit int) *SearchPage {
+	if page < 1 {
+		page = 1
+	}
+	result := &SearchPage{Limit: limit, Total: len(snippets), Snippets: snippets}
+	if limit > 0 {
+		result.Offset = min((page-1)*limit, len(snippets))
+		end := min(result.Offset+limit, len(snippets))
+		result.Snippets = snippets[result.Offset:end]
+		result.HasMore = end < len(snippets)
+	}
+	return result
+}
+
+// SearchRemote returns page number page (starting at 1) of limit results of a
+// query. Without client side filters the page is requested directly; otherwise
+// results are filtered from larger API pages and earlier pages are skipped.
+func SearchRemote(q *Query, page, limit int, apiKey string) (*SearchPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+	it := q.Iterate(offset, limit, apiKey)
+	skip := 0
+	if q.needsClientFilter() {
+		it = q.Iterate(0, min(limit*3, maxAPILimit), apiKey)
+		skip = offset
+	}
+
+	for ; skip > 0; skip-- {
+		if _, ok := it.Next(); !ok {
+			break
+		}
+	}
+	result := &SearchPage{Offset: offset, Limit: limit}
+	for len(result.Snippets) < limit {
+		snippet, ok := it.Next()
+		if !ok {
+			break
+		}
+		result.Snippets = append(result.Snippets, snippet)
+	}
+	if it.Err() != nil {
+		return nil, it.Err()
+	}
+
+	// Look one result ahead to know whether there is another page
+	if len(result.Snippets) == limit {
+		if next, ok := it.Next(); ok {
+			result.HasMore = true
+			it.buffer = append([]Snippet{next}, it.buffer...)
+		}
+	}
+	if !q.needsClientFilter() {
+		result.Total = it.Total()
+	}
+	return result, nil
+}
+
+// ValidSort reports whether order is a known sort order
+func ValidSort(order string) bool {
+	switch order {
+	case "", SortRelevance, SortUpdated, SortTitle:
+		return true
+	}
+	return false
+}
+
+// SortSnippets orders snippets in place. Relevance keeps the order they were
+// ranked in.
+func SortSnippets(snippets []Snippet, order string) {
+	switch order {
+	case SortUpdated:
+		sort.SliceStable(snippets, func(i, j int) bool {
+			return snippets[i].UpdatedAt.After(snippets[j].UpdatedAt)
+		})
+	case SortTitle:
+		sort.SliceStable(snippets, func(i, j int) bool {
+			return strings.ToLower(snippets[i].Title) < strings.ToLower(snippets[j].Title)
+		})
+	}
+}
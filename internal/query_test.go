@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Query
+	}{
+		{"auth middleware", Query{Terms: []string{"auth", "middleware"}}},
+		{`"exact phrase" -"not this"`, Query{Phrases: []string{"exact phrase"}, ExcludePhrases: []string{"not this"}}},
+		{"lang:go,rust -lang:c", Query{Langs: []string{"go", "rust"}, ExcludeLangs: []string{"c"}}},
+		{"tag:http,grpc tag:auth -tag:deprecated", Query{Tags: [][]string{{"http", "grpc"}, {"auth"}}, ExcludeTags: []string{"deprecated"}}},
+		{"path:handlers/ -path:vendor/", Query{Paths: []string{"handlers/"}, ExcludePaths: []string{"vendor/"}}},
+		{"-legacy owner:me", Query{Terms: []string{"owner:me"}, ExcludeTerms: []string{"legacy"}}},
+		{"lang:", Query{Terms: []string{"lang:"}}},
+		// A lone '-' or empty quotes must not become a term that matches everything
+		{"- foo", Query{Terms: []string{"foo"}}},
+		{"foo -", Query{Terms: []string{"foo"}}},
+		{`- -"" ""`, Query{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseQuery(tt.input)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) error: %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("ParseQuery(%q) = %+v, want %+v", tt.input, *got, tt.want)
+		}
+	}
+
+	if _, err := ParseQuery(`foo "unterminated`); err == nil {
+		t.Error("ParseQuery accepted an unterminated quote")
+	}
+}
+
+func TestQueryMatches(t *testing.T) {
+	snippet := &Snippet{
+		Title:    "Retry middleware",
+		Code:     "func Retry(next http.Handler) http.Handler",
+		Language: "Go",
+		Path:     "handlers/retry.go",
+		Tags:     []string{"http", "resilience"},
+	}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"anything", true}, // Plain terms only rank
+		{"lang:go", true},
+		{"lang:rust,go", true},
+		{"lang:rust", false},
+		{"-lang:GO", false},
+		{"tag:http tag:resilience", true},
+		{"tag:http tag:auth", false},
+		{"tag:auth,resilience", true},
+		{"-tag:http", false},
+		{"path:handlers/", true},
+		{"path:cmd/", false},
+		{"-path:retry", false},
+		{`"next http.handler"`, true},
+		{`"no such phrase"`, false},
+		{`-"retry middleware"`, false},
+		{"-retry", false},
+		{"-resilience", false},
+		{"-grpc", true},
+		{"- retry", true},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := q.Matches(snippet); got != tt.want {
+			t.Errorf("%q matches = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPageOfSortedSnippets(t *testing.T) {
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var snippets []Snippet
+	for i, title := range []string{"c", "e", "a", "d", "b"} {
+		snippets = append(snippets, Snippet{Title: title, UpdatedAt: day.AddDate(0, 0, i)})
+	}
+	SortSnippets(snippets, SortTitle)
+
+	var titles []string
+	for page := 1; ; page++ {
+		result := PageOf(snippets, page, 2)
+		if result.Total != 5 || result.Offset != (page-1)*2 {
+			t.Fatalf("page %d: total %d offset %d", page, result.Total, result.Offset)
+		}
+		for _, s := range result.Snippets {
+			titles = append(titles, s.Title)
+		}
+		if !result.HasMore {
+			break
+		}
+	}
+	if want := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("paged titles = %v, want %v", titles, want)
+	}
+
+	SortSnippets(snippets, SortUpdated)
+	if got := PageOf(snippets, 1, 1).Snippets[0].Title; got != "b" {
+		t.Errorf("newest snippet = %q, want b", got)
+	}
+	if result := PageOf(snippets, 9, 2); len(result.Snippets) != 0 || result.HasMore {
+		t.Errorf("page past the end = %+v", result)
+	}
+	if result := PageOf(snippets, 1, 0); len(result.Snippets) != 5 {
+		t.Errorf("limit 0 returned %d snippets, want all", len(result.Snippets))
+	}
+}
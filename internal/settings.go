@@ -0,0 +1,448 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/spf13/viper"
+)
+
+// Setting types
+const (
+	SettingString   = "string"
+	SettingBool     = "bool"
+	SettingInt      = "int"
+	SettingDuration = "duration"
+	SettingSize     = "size"
+	SettingEnum     = "enum"
+)
+
+// ErrUnknownSetting is returned for keys missing from the settings schema
+var ErrUnknownSetting = errors.New("unknown setting")
+
+// Setting describes a config key. A "*" segment in the key matches any name,
+// e.g. the profile in profiles.*.base_url.
+type Setting struct {
+	Key         string   `json:"key" yaml:"key"`
+	Type        string   `json:"type" yaml:"type"`
+	Default     string   `json:"default,omitempty" yaml:"default,omitempty"`
+	Description string   `json:"description" yaml:"description"`
+	Allowed     []string `json:"allowed,omitempty" yaml:"allowed,omitempty"`
+	Secret      bool     `json:"secret,omitempty" yaml:"secret,omitempty"`
+
+	// Command and Flag make the setting a default for a command's flag
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+	Flag    string `json:"flag,omitempty" yaml:"flag,omitempty"`
+
+	// Project settings may be set by a project config. The others choose
+	// where tokens are sent or which programs run, so a repository can't.
+	Project bool `json:"project,omitempty" yaml:"project,omitempty"`
+
+	validate func(value string) error
+}
+
+// settings is the schema of the config file
+var settings = []Setting{
+	{Key: "api_key", Type: SettingString, Secret: true, Description: "API token of the default profile (set by login)"},
+	{Key: "base_url", Type: SettingString, Description: "API base URL overriding the active profile's, e.g. from SNIPPETKIT_BASE_URL", validate: ValidateBaseURL},
+	{Key: "current_profile", Type: SettingString, Default: DefaultProfile, Description: "Profile used without --profile (set by profile use)",
+		validate: func(value string) error {
+			if !ProfileExists(value) {
+				return fmt.Errorf("%w: %s", ErrProfileNotFound, value)
+			}
+			return nil
+		}},
+	{Key: "credential_helper", Type: SettingString, Default: CredentialPlaintext, Description: "Where tokens are stored: plaintext (the config file), file (encrypted) or a snippetkit-credential-<name> helper",
+		validate: ValidateCredentialHelper},
+	{Key: "logging_enabled", Type: SettingBool, Default: "true", Description: "Write logs to the log directory"},
+	{Key: "log_level", Type: SettingEnum, Default: "info", Allowed: []string{"debug", "info", "warn", "error"}, Description: "Minimum level of logged messages"},
+	{Key: "no_color", Type: SettingBool, Default: "false", Description: "Disable colors"},
+	{Key: "offline", Type: SettingBool, Default: "false", Description: "Serve snippets strictly from the local cache"},
+	{Key: "cache.ttl", Type: SettingDuration, Default: defaultCacheTTL.String(), Description: "How long cached responses are used without revalidation"},
+	{Key: "cache.max_size", Type: SettingSize, Default: "50MB", Description: "Size cap of the cache, e.g. 100MB"},
+	{Key: "preview_lines", Type: SettingInt, Default: strconv.Itoa(defaultPreviewLines), Description: "Lines of code info shows without --full",
+		Project: true, validate: positive},
+	{Key: "theme", Type: SettingString, Description: "Syntax highlighting theme (see 'snippetkit themes')", Project: true,
+		validate: func(value string) error {
+			if _, ok := styles.Registry[value]; !ok {
+				return fmt.Errorf("unknown theme %q (run 'snippetkit themes --list' to see them)", value)
+			}
+			return nil
+		}},
+	{Key: "ui_theme", Type: SettingString, Default: "auto", Description: "Theme of the CLI's output: auto or a UI theme (see 'snippetkit themes --ui')", Project: true,
+		validate: func(value string) error {
+			if value == "auto" {
+				return nil
+			}
+			_, err := ResolveUITheme(value)
+			return err
+		}},
+	{Key: "ui_themes.*.base", Type: SettingEnum, Allowed: []string{"dark", "light", "high-contrast", "monochrome"}, Description: "Built-in theme a user theme starts from (default dark)"},
+	{Key: "profiles.*.api_key", Type: SettingString, Secret: true, Description: "API token of a profile (set by login --profile)"},
+	{Key: "profiles.*.base_url", Type: SettingString, Default: DefaultBaseURL, Description: "API base URL of a profile", validate: ValidateBaseURL},
+	{Key: "profiles.*.credential_helper", Type: SettingString, Description: "Where the token of a profile is stored, see credential_helper", validate: ValidateCredentialHelper},
+	{Key: "profiles.*.defaults.*", Type: SettingString, Description: "Flag default of a profile"},
+
+	// Command defaults
+	{Key: "search.limit", Type: SettingInt, Default: "10", Command: "search", Flag: "limit", Project: true, Description: "Results per page of search", validate: positive},
+	{Key: "search.sort", Type: SettingEnum, Default: SortRelevance, Allowed: []string{SortRelevance, SortUpdated, SortTitle}, Command: "search", Flag: "sort", Project: true, Description: "Sort order of search"},
+	{Key: "search.lang", Type: SettingString, Command: "search", Flag: "lang", Project: true, Description: "Language filter of search"},
+	{Key: "search.preview", Type: SettingBool, Default: "false", Command: "search", Flag: "preview", Project: true, Description: "Show the first line of code in search results"},
+	{Key: "add.path", Type: SettingString, Command: "add", Flag: "path", Project: true, Description: "Install path of add"},
+	{Key: "add.merge", Type: SettingBool, Default: "false", Command: "add", Flag: "merge", Project: true, Description: "Merge Go snippets into existing files"},
+	{Key: "info.line_numbers", Type: SettingBool, Default: "false", Command: "info", Flag: "line-numbers", Project: true, Description: "Show line numbers in info"},
+}
+
+func init() {
+	// The colors of user UI themes, see UITheme
+	for _, field := range []string{"title", "label", "success", "error", "warning", "info", "url", "divider"} {
+		settings = append(settings, Setting{Key: "ui_themes.*." + field, Type: SettingString, Description: "The " + field + " color of a user UI theme"})
+	}
+	settings = append(settings, Setting{Key: "ui_themes.*.highlight", Type: SettingString, Description: "Highlighting theme suggested by a user UI theme"})
+}
+
+func positive(value string) error {
+	if n, _ := strconv.Atoi(value); n <= 0 {
+		return fmt.Errorf("expected a positive number, got %q", value)
+	}
+	return nil
+}
+
+// Settings returns the schema of the config file
+func Settings() []Setting {
+	return settings
+}
+
+// ProjectSetting reports whether a project config may set key
+func ProjectSetting(key string) bool {
+	setting, err := LookupSetting(key)
+	return err == nil && setting.Project
+}
+
+// LookupSetting returns the schema of a config key. Unknown keys get an
+// error suggesting the closest known key.
+func LookupSetting(key string) (*Setting, error) {
+	key = strings.ToLower(key)
+	for i := range settings {
+		if matchSettingKey(settings[i].Key, key) {
+			return &settings[i], nil
+		}
+	}
+
+	best, bestDistance := "", 4
+	for _, setting := range settings {
+		if strings.Contains(setting.Key, "*") {
+			continue
+		}
+		if d := levenshtein(key, setting.Key, 3); d < bestDistance {
+			best, bestDistance = setting.Key, d
+		}
+	}
+	if best != "" {
+		return nil, fmt.Errorf("%w %q (did you mean %s?)", ErrUnknownSetting, key, best)
+	}
+	return nil, fmt.Errorf("%w %q (run 'snippetkit config list --all' to see the settings)", ErrUnknownSetting, key)
+}
+
+// matchSettingKey matches a key against a schema key with "*" segments
+func matchSettingKey(pattern, key string) bool {
+	patternParts, keyParts := strings.Split(pattern, "."), strings.Split(key, ".")
+	if len(patternParts) != len(keyParts) {
+		return false
+	}
+	for i, part := range patternParts {
+		if keyParts[i] == "" || (part != "*" && part != keyParts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Parse validates a value for the setting and converts it to the type stored
+// in the config file
+func (s *Setting) Parse(value string) (interface{}, error) {
+	var parsed interface{} = value
+	switch s.Type {
+	case SettingBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false, got %q", value)
+		}
+		parsed = b
+	case SettingInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number, got %q", value)
+		}
+		parsed = n
+	case SettingDuration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("expected a duration such as 12h or 30m, got %q", value)
+		}
+	case SettingSize:
+		if _, err := ParseSize(value); err != nil {
+			return nil, fmt.Errorf("expected a size such as 512KB or 50MB, got %q", value)
+		}
+	case SettingEnum:
+		if !containsValue(s.Allowed, value) {
+			return nil, fmt.Errorf("expected one of %s, got %q", strings.Join(s.Allowed, ", "), value)
+		}
+	}
+	if s.validate != nil {
+		if err := s.validate(value); err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// SettingValue is the value of a config key
+type SettingValue struct {
+	Key    string
+	Value  string
+	IsSet  bool   // false when Value is the default
+	Origin string // Where the value comes from, see SettingOrigin
+	Source string // The flag, environment variable or file of the origin
+	*Setting
+}
+
+// Setting origins, from the highest precedence to the lowest
+const (
+	OriginFlag    = "flag"
+	OriginEnv     = "env"
+	OriginProject = "project"
+	OriginUser    = "user"
+	OriginDefault = "default"
+)
+
+// SettingOrigin returns where the value of a key comes from, and the flag,
+// environment variable or config file that sets it
+func SettingOrigin(key string) (origin, source string) {
+	if flag, ok := boundFlags[key]; ok && flag.Changed {
+		return OriginFlag, "--" + flag.Name
+	}
+	if _, ok := os.LookupEnv(EnvVar(key)); ok {
+		return OriginEnv, EnvVar(key)
+	}
+	if ProjectSetting(key) && configFileHas(projectConfigPath, key) {
+		return OriginProject, projectConfigPath
+	}
+	if configFileHas(userConfigPath, key) {
+		return OriginUser, userConfigPath
+	}
+	return OriginDefault, ""
+}
+
+// configFileHas reports whether a config file sets key
+func configFileHas(path, key string) bool {
+	if path == "" {
+		return false
+	}
+	config, err := readConfigFile(path)
+	if err != nil {
+		return false
+	}
+	values := map[string]interface{}{}
+	flattenConfig("", config, values)
+	_, ok := values[key]
+	return ok
+}
+
+// GetSetting returns the effective value of a config key
+func GetSetting(key string) (*SettingValue, error) {
+	key = strings.ToLower(key)
+	setting, err := LookupSetting(key)
+	if err != nil {
+		return nil, err
+	}
+	value := &SettingValue{Key: key, Value: setting.Default, Setting: setting}
+	value.Origin, value.Source = SettingOrigin(key)
+	if viper.IsSet(key) {
+		value.Value = formatSettingValue(viper.Get(key))
+		value.IsSet = true
+	}
+	return value, nil
+}
+
+// ConfiguredSettings returns the value of every setting without wildcards and
+// of the wildcard settings present in the config
+func ConfiguredSettings() []SettingValue {
+	keys := map[string]bool{}
+	for _, setting := range settings {
+		if !strings.Contains(setting.Key, "*") {
+			keys[setting.Key] = true
+		}
+	}
+	for _, key := range viper.AllKeys() {
+		keys[key] = true
+	}
+
+	var values []SettingValue
+	for key := range keys {
+		value, err := GetSetting(key)
+		if err != nil {
+			continue // Flags and unknown keys aren't settings
+		}
+		values = append(values, *value)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Key < values[j].Key })
+	return values
+}
+
+// ValidateConfigFile checks the config file against the schema and returns a
+// problem for every unknown key or invalid value
+func ValidateConfigFile() ([]error, error) {
+	return validateConfig(configFilePath())
+}
+
+// validateConfig checks a config file against the schema
+func validateConfig(path string) ([]error, error) {
+	config, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	flattenConfig("", config, values)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []error
+	for _, key := range keys {
+		setting, err := LookupSetting(key)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if _, err := setting.Parse(formatSettingValue(values[key])); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return problems, nil
+}
+
+// flattenConfig collects the leaves of a config map under their dotted keys
+func flattenConfig(prefix string, config map[string]interface{}, values map[string]interface{}) {
+	for name, value := range config {
+		key := strings.ToLower(name)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenConfig(key, nested, values)
+			continue
+		}
+		values[key] = value
+	}
+}
+
+func formatSettingValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}, map[string]interface{}:
+		return fmt.Sprintf("%v", v)
+	}
+	return fmt.Sprint(value)
+}
+
+// MaskSecret hides all but the last four characters of a secret
+func MaskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 8 {
+		return "********"
+	}
+	return "********" + value[len(value)-4:]
+}
+
+// SetSetting validates value and writes it to the config file
+func SetSetting(key, value string) error {
+	key = strings.ToLower(key)
+	setting, err := LookupSetting(key)
+	if err != nil {
+		return err
+	}
+	parsed, err := setting.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return UpdateConfigFile(func(config map[string]interface{}) error {
+		parts := strings.Split(key, ".")
+		parent := config
+		for _, part := range parts[:len(parts)-1] {
+			parent = configMap(parent, part)
+		}
+		parent[parts[len(parts)-1]] = parsed
+		return nil
+	})
+}
+
+// UnsetSetting removes a key from the config file. It reports whether the key
+// was set.
+func UnsetSetting(key string) (bool, error) {
+	key = strings.ToLower(key)
+	if _, err := LookupSetting(key); err != nil {
+		return false, err
+	}
+	removed := false
+	err := UpdateConfigFile(func(config map[string]interface{}) error {
+		removed = deleteConfigKey(config, strings.Split(key, "."))
+		return nil
+	})
+	return removed, err
+}
+
+// deleteConfigKey removes a dotted key, dropping maps it leaves empty
+func deleteConfigKey(config map[string]interface{}, parts []string) bool {
+	if len(parts) == 1 {
+		_, ok := config[parts[0]]
+		delete(config, parts[0])
+		return ok
+	}
+	nested, ok := config[parts[0]].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	removed := deleteConfigKey(nested, parts[1:])
+	if len(nested) == 0 {
+		delete(config, parts[0])
+	}
+	return removed
+}
+
+// CommandDefaults returns the configured flag defaults of a command
+func CommandDefaults(command string) map[string]string {
+	defaults := map[string]string{}
+	for _, setting := range settings {
+		if setting.Command == command && viper.IsSet(setting.Key) {
+			defaults[setting.Flag] = formatSettingValue(viper.Get(setting.Key))
+		}
+	}
+	return defaults
+}
+
+// ConfigFile returns the path of the config file settings are written to
+func ConfigFile() string {
+	return configFilePath()
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLookupSetting(t *testing.T) {
+	for _, key := range []string{"search.limit", "SEARCH.LIMIT", "profiles.work.base_url", "ui_themes.mine.title"} {
+		if _, err := LookupSetting(key); err != nil {
+			t.Errorf("LookupSetting(%q) error: %v", key, err)
+		}
+	}
+
+	tests := []struct {
+		key     string
+		suggest string
+	}{
+		{"serch.limit", "did you mean search.limit?"},
+		{"log_levl", "did you mean log_level?"},
+		{"profiles..base_url", "config list --all"},
+		{"profiles.work.base_url.extra", "config list --all"},
+		{"nothing_like_it", "config list --all"},
+	}
+	for _, tt := range tests {
+		_, err := LookupSetting(tt.key)
+		if !errors.Is(err, ErrUnknownSetting) {
+			t.Errorf("LookupSetting(%q) error = %v, want ErrUnknownSetting", tt.key, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.suggest) {
+			t.Errorf("LookupSetting(%q) error = %q, want it to contain %q", tt.key, err, tt.suggest)
+		}
+	}
+}
+
+func TestSettingParse(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		want  interface{}
+		ok    bool
+	}{
+		{"search.limit", "20", 20, true},
+		{"search.limit", "0", nil, false},
+		{"search.limit", "ten", nil, false},
+		{"no_color", "true", true, true},
+		{"no_color", "yes", nil, false},
+		{"cache.ttl", "12h", "12h", true},
+		{"cache.ttl", "12", nil, false},
+		{"cache.max_size", "100MB", "100MB", true},
+		{"cache.max_size", "lots", nil, false},
+		{"log_level", "warn", "warn", true},
+		{"log_level", "verbose", nil, false},
+		{"theme", "monokai", "monokai", true},
+		{"theme", "no-such-theme", nil, false},
+		{"base_url", "https://snippets.example.com", "https://snippets.example.com", true},
+		{"base_url", "ftp://snippets.example.com", nil, false},
+		{"credential_helper", "pass", "pass", true},
+		{"credential_helper", "/usr/bin/pass", nil, false},
+	}
+	for _, tt := range tests {
+		setting, err := LookupSetting(tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := setting.Parse(tt.value)
+		if (err == nil) != tt.ok {
+			t.Errorf("Parse(%s=%q) error = %v, want ok %v", tt.key, tt.value, err, tt.ok)
+			continue
+		}
+		if tt.ok && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%s=%q) = %#v, want %#v", tt.key, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestValidateConfigFile(t *testing.T) {
+	loadTestConfig(t, `
+search:
+  limit: -1
+  sort: title
+log_levl: debug
+cache:
+  ttl: 1h
+profiles:
+  work:
+    base_url: not a url
+`, "")
+
+	problems, err := ValidateConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, problem := range problems {
+		got = append(got, problem.Error())
+	}
+	want := []string{"log_levl", "profiles.work.base_url", "search.limit"}
+	if len(got) != len(want) {
+		t.Fatalf("ValidateConfigFile() = %q, want problems with %v", got, want)
+	}
+	for i, key := range want {
+		if !strings.Contains(got[i], key) {
+			t.Errorf("problem %d = %q, want one about %s", i, got[i], key)
+		}
+	}
+}
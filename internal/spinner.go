@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// progressEnabled is turned off by --quiet and when output is meant for programs
+var progressEnabled = true
+
+// SetProgressEnabled turns spinners and status lines on or off
+func SetProgressEnabled(enabled bool) {
+	progressEnabled = enabled
+}
+
+// ProgressEnabled reports whether spinners and status lines are shown
+func ProgressEnabled() bool {
+	return progressEnabled
+}
+
+// spinnerFrames returns the animation frames for the current platform
+func spinnerFrames() []string {
+	if runtime.GOOS == "windows" {
+		return []string{"|", "/", "-", "\\"}
+	}
+	return []string{"⣾", "⣽", "⣻", "⢿", "⡿", "⣟", "⣯", "⣷"}
+}
+
+// spinnerSymbols returns the success and error symbols for the current platform
+func spinnerSymbols() (string, string) {
+	if runtime.GOOS == "windows" {
+		return ">", "!"
+	}
+	return "✓", "✗"
+}
+
+// Spinner shows progress for a long running step on stderr. When stderr isn't
+// a terminal it degrades to plain status lines so logs stay readable.
+type Spinner struct {
+	message  string
+	silent   bool
+	animated bool
+	stop     chan struct{}
+	done     sync.WaitGroup
+}
+
+// NewSpinner creates a spinner suited to the current output
+func NewSpinner() *Spinner {
+	return &Spinner{
+		silent:   !progressEnabled,
+		animated: IsTerminal(os.Stderr),
+	}
+}
+
+// Start starts the spinner with the given message
+func (s *Spinner) Start(message string) {
+	s.message = message
+	if s.silent {
+		return
+	}
+	if !s.animated {
+		fmt.Fprintln(Stderr, message)
+		return
+	}
+
+	s.stop = make(chan struct{})
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+		frames := spinnerFrames()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			fmt.Fprintf(Stderr, "\r%s %s", frames[i%len(frames)], message)
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				// Clear the line for the final message
+				fmt.Fprint(Stderr, "\r\033[K")
+				return
+			}
+		}
+	}()
+}
+
+// finish stops the animation and prints the final line
+func (s *Spinner) finish(symbol string, style lipgloss.Style, prefix, message string) {
+	if s.silent {
+		return
+	}
+	if !s.animated {
+		fmt.Fprintln(Stderr, prefix+message)
+		return
+	}
+	if s.stop != nil {
+		close(s.stop)
+		s.done.Wait()
+		s.stop = nil
+	}
+	fmt.Fprintln(Stderr, style.Render(symbol+" "+message))
+}
+
+// Success stops the spinner with a success message
+func (s *Spinner) Success(message string) {
+	success, _ := spinnerSymbols()
+	s.finish(success, activeUITheme.Style(activeUITheme.Success), "ok: ", message)
+}
+
+// Error stops the spinner with an error message
+func (s *Spinner) Error(message string) {
+	_, failure := spinnerSymbols()
+	s.finish(failure, activeUITheme.Style(activeUITheme.Error), "error: ", message)
+}
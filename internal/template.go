@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// templateVarPattern matches template variables in snippet code, written as
+// {{var:name}} or {{var:name|default}}. The var: prefix keeps them apart from
+// the {{ }} of Go, Vue or Handlebars templates inside snippets.
+var templateVarPattern = regexp.MustCompile(`\{\{var:([A-Za-z_][A-Za-z0-9_-]*)(?:\|([^}]*))?\}\}`)
+
+// TemplateVar is a variable used in snippet code
+type TemplateVar struct {
+	Name       string
+	Default    string
+	HasDefault bool
+}
+
+// TemplateVars returns the variables used in code, in order of first use
+func TemplateVars(code string) []TemplateVar {
+	var vars []TemplateVar
+	seen := map[string]bool{}
+	for _, match := range templateVarPattern.FindAllStringSubmatchIndex(code, -1) {
+		name := code[match[2]:match[3]]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		v := TemplateVar{Name: name}
+		if match[4] >= 0 {
+			v.Default, v.HasDefault = code[match[4]:match[5]], true
+		}
+		vars = append(vars, v)
+	}
+	return vars
+}
+
+// RenderTemplate replaces the template variables in code with values, falling
+// back to their defaults. It fails when a variable has neither.
+func RenderTemplate(code string, values map[string]string) (string, error) {
+	var missing []string
+	for _, v := range TemplateVars(code) {
+		if _, ok := values[v.Name]; !ok && !v.HasDefault {
+			missing = append(missing, v.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing template variables: %s (set them with --var name=value)", strings.Join(missing, ", "))
+	}
+
+	return templateVarPattern.ReplaceAllStringFunc(code, func(match string) string {
+		parts := templateVarPattern.FindStringSubmatch(match)
+		if value, ok := values[parts[1]]; ok {
+			return value
+		}
+		return parts[2]
+	}), nil
+}
+
+// ParseTemplateValues parses name=value pairs given with --var
+func ParseTemplateValues(pairs []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid variable %q (expected name=value)", pair)
+		}
+		values[strings.TrimSpace(name)] = value
+	}
+	return values, nil
+}
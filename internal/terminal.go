@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"strconv"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/viper"
+)
+
+// ErrNoInput is returned when input is required but prompting is not possible
+var ErrNoInput = errors.New("input required but prompting is disabled (use --yes or pass the value as a flag)")
+
+// IsTerminal reports whether f is attached to a terminal
+func IsTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// CanPrompt reports whether the user can be asked for input. Prompting is
+// disabled by --no-input or when stdin or stdout isn't a terminal.
+func CanPrompt() bool {
+	if viper.GetBool("no_input") {
+		return false
+	}
+	return IsTerminal(os.Stdin) && IsTerminal(os.Stdout)
+}
+
+// AssumeYes reports whether confirmations should be answered with yes and
+// other prompts with their defaults
+func AssumeYes() bool {
+	return viper.GetBool("yes")
+}
+
+// defaultTerminalWidth is used when the width of stdout can't be determined
+const defaultTerminalWidth = 80
+
+// TerminalSize returns the width and height of the terminal on stdout. When
+// stdout isn't a terminal, COLUMNS and LINES are used if set, and the height is
+// zero otherwise.
+func TerminalSize() (width, height int) {
+	if IsTerminal(os.Stdout) {
+		if w, h, err := term.GetSize(os.Stdout.Fd()); err == nil && w > 0 {
+			return w, h
+		}
+	}
+	width, height = defaultTerminalWidth, 0
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		width = w
+	}
+	if h, err := strconv.Atoi(os.Getenv("LINES")); err == nil && h > 0 {
+		height = h
+	}
+	return width, height
+}
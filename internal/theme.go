@@ -0,0 +1,193 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/colorprofile"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/viper"
+)
+
+// UITheme is the palette the CLI renders its own output with. Colors are hex
+// values or ANSI color numbers; an empty color keeps the terminal's default.
+type UITheme struct {
+	Name      string `mapstructure:"-"`
+	Base      string `mapstructure:"base"` // Built-in theme a user theme starts from
+	Title     string `mapstructure:"title"`
+	Label     string `mapstructure:"label"`
+	Success   string `mapstructure:"success"`
+	Error     string `mapstructure:"error"`
+	Warning   string `mapstructure:"warning"`
+	Info      string `mapstructure:"info"`
+	URL       string `mapstructure:"url"`
+	Divider   string `mapstructure:"divider"`
+	Highlight string `mapstructure:"highlight"` // Default chroma style, see Theme
+}
+
+// Built-in UI themes
+var uiThemes = map[string]UITheme{
+	"dark": {
+		Title:     "#ea580c", // Orange
+		Label:     "#fbbf24", // Light orange
+		Success:   "#228B22", // Green
+		Error:     "#ef4444", // Red
+		Warning:   "#f59e0b", // Yellow
+		Info:      "#6b7280", // Gray
+		URL:       "#3b82f6", // Blue
+		Divider:   "#444",
+		Highlight: "onedark",
+	},
+	"light": {
+		Title:     "#c2410c",
+		Label:     "#b45309",
+		Success:   "#15803d",
+		Error:     "#b91c1c",
+		Warning:   "#a16207",
+		Info:      "#4b5563",
+		URL:       "#1d4ed8",
+		Divider:   "#d1d5db",
+		Highlight: "github",
+	},
+	"high-contrast": {
+		Title:     "11", // Bright yellow
+		Label:     "14", // Bright cyan
+		Success:   "10",
+		Error:     "9",
+		Warning:   "11",
+		Info:      "15",
+		URL:       "12",
+		Divider:   "15",
+		Highlight: "monokai",
+	},
+	"monochrome": {
+		Highlight: "bw",
+	},
+}
+
+// activeUITheme is the theme selected by SetUITheme
+var activeUITheme = namedUITheme("dark")
+
+func namedUITheme(name string) *UITheme {
+	theme := uiThemes[name]
+	theme.Name = name
+	return &theme
+}
+
+// UIThemes returns the names of the built-in and configured UI themes
+func UIThemes() []string {
+	names := []string{}
+	for name := range uiThemes {
+		names = append(names, name)
+	}
+	for name := range viper.GetStringMap("ui_themes") {
+		if _, builtin := uiThemes[name]; !builtin {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadUITheme returns the UI theme selected by ui_theme. "auto", the default,
+// picks dark or light from the terminal background. User themes are defined
+// under ui_themes and override the colors of their base theme:
+//
+//	ui_theme: mine
+//	ui_themes:
+//	  mine:
+//	    base: light
+//	    title: "#9333ea"
+func LoadUITheme() (*UITheme, error) {
+	name := strings.ToLower(strings.TrimSpace(viper.GetString("ui_theme")))
+	if name == "" || name == "auto" {
+		name = detectBackground()
+	}
+	return ResolveUITheme(name)
+}
+
+// ResolveUITheme looks up a built-in or configured UI theme by name
+func ResolveUITheme(name string) (*UITheme, error) {
+	if _, builtin := uiThemes[name]; builtin {
+		return namedUITheme(name), nil
+	}
+
+	key := "ui_themes." + name
+	if !viper.IsSet(key) {
+		return nil, fmt.Errorf("unknown ui_theme %q (available: %s)", name, strings.Join(UIThemes(), ", "))
+	}
+	var custom UITheme
+	if err := viper.UnmarshalKey(key, &custom); err != nil {
+		return nil, fmt.Errorf("invalid ui theme %q: %v", name, err)
+	}
+
+	// Only ui_theme auto asks the terminal for its background
+	base := custom.Base
+	if base == "" {
+		base = "dark"
+	}
+	if _, builtin := uiThemes[base]; !builtin {
+		return nil, fmt.Errorf("ui theme %q: unknown base theme %q", name, base)
+	}
+	theme := namedUITheme(base)
+	theme.Name, theme.Base = name, base
+	for _, field := range []struct {
+		value string
+		dst   *string
+	}{
+		{custom.Title, &theme.Title},
+		{custom.Label, &theme.Label},
+		{custom.Success, &theme.Success},
+		{custom.Error, &theme.Error},
+		{custom.Warning, &theme.Warning},
+		{custom.Info, &theme.Info},
+		{custom.URL, &theme.URL},
+		{custom.Divider, &theme.Divider},
+		{custom.Highlight, &theme.Highlight},
+	} {
+		if field.value != "" {
+			*field.dst = field.value
+		}
+	}
+	return theme, nil
+}
+
+// detectBackground returns "light" on terminals with a light background and
+// "dark" otherwise. The terminal is only asked when it is attached to stdin
+// and stdout and colors are on; without an answer the query waits for a
+// timeout. The answer is kept for the rest of the run.
+var detectBackground = sync.OnceValue(func() string {
+	if !IsTerminal(os.Stdin) || !IsTerminal(os.Stdout) {
+		return "dark"
+	}
+	if max(StreamColorProfile(os.Stdout), StreamColorProfile(os.Stderr)) < colorprofile.ANSI {
+		return "dark"
+	}
+	if !lipgloss.HasDarkBackground() {
+		return "light"
+	}
+	return "dark"
+})
+
+// SetUITheme makes theme the active UI theme
+func SetUITheme(theme *UITheme) {
+	activeUITheme = theme
+}
+
+// ActiveUITheme returns the active UI theme
+func ActiveUITheme() *UITheme {
+	return activeUITheme
+}
+
+// Style returns a lipgloss style with color as foreground, or an unstyled one
+// when color is empty
+func (t *UITheme) Style(color string) lipgloss.Style {
+	style := lipgloss.NewStyle()
+	if color != "" {
+		style = style.Foreground(lipgloss.Color(color))
+	}
+	return style
+}
@@ -5,10 +5,20 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"github.com/charmbracelet/x/term"
 )
 
-// YesNoPrompt asks yes/no questions using the label.
+// YesNoPrompt asks yes/no questions using the label. --yes answers yes;
+// without a terminal to ask on the default is taken.
 func YesNoPrompt(label string, def bool) bool {
+	if AssumeYes() {
+		return true
+	}
+	if !CanPrompt() {
+		return def
+	}
+
 	choices := "Y/n"
 	if !def {
 		choices = "y/N"
@@ -36,7 +46,11 @@ func YesNoPrompt(label string, def bool) bool {
 
 // GetUserInput prompts the user for input with a default value.
 func GetUserInput(prompt string, defaultValue string) string {
-	fmt.Printf("%s [%s]: ", prompt, defaultValue)
+	if AssumeYes() || !CanPrompt() {
+		return defaultValue
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [%s]: ", prompt, defaultValue)
 	r := bufio.NewReader(os.Stdin)
 	input, _ := r.ReadString('\n')
 	input = strings.TrimSpace(input)
@@ -46,3 +60,17 @@ func GetUserInput(prompt string, defaultValue string) string {
 	}
 	return input
 }
+
+// PasswordPrompt asks for a secret without echoing it
+func PasswordPrompt(label string) (string, error) {
+	if !CanPrompt() {
+		return "", ErrNoInput
+	}
+	fmt.Fprintf(os.Stderr, "%s: ", label)
+	secret, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read input: %v", err)
+	}
+	return string(secret), nil
+}
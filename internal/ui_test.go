@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestYesNoPromptAssumeYes(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	// Tests have no terminal, so the prompt falls back to its default
+	for _, def := range []bool{true, false} {
+		if got := YesNoPrompt("Continue?", def); got != def {
+			t.Errorf("YesNoPrompt(%v) without a terminal = %v", def, got)
+		}
+	}
+	viper.Set("yes", true)
+	if !YesNoPrompt("Continue?", false) {
+		t.Error("YesNoPrompt with --yes = false, want true")
+	}
+}
+
+func TestResolveUIThemeDefaultsToDarkBase(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	viper.Set("ui_themes.mine.title", "#9333ea")
+	theme, err := ResolveUITheme("mine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if theme.Base != "dark" || theme.Title != "#9333ea" {
+		t.Errorf("ResolveUITheme(mine) = base %q title %q, want dark and #9333ea", theme.Base, theme.Title)
+	}
+}